@@ -0,0 +1,177 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/prometheus"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// ActionDeprecation Action(版本)弃用信息, 请求时输出 Deprecation/Sunset/Link 应答头
+	ActionDeprecation struct {
+		Since  time.Time //弃用时间, 为零值时 Deprecation 头为 true
+		Sunset time.Time //下线时间, 为零值时不输出 Sunset 头
+		Link   string    //迁移说明文档地址, 以 Link: <url>; rel="deprecation" 输出
+	}
+)
+
+const (
+	actionVersionContextKey = "landau_action_version"
+)
+
+var (
+	httpActionVersionEntry   = make(map[string]map[string]httpHandleEntry) //key: action, version
+	actionDefaultVersion     = make(map[string]string)                     //key: action
+	actionDeprecation        = make(map[string]*ActionDeprecation)         //key: action + "\n" + version
+	syncActionVersion        = sync.RWMutex{}
+	actionVersionHeader      = "X-Api-Version"
+	actionVersionNotFoundRsp = gin.H{"Code": 161, "Message": "Unsupported Action Version"}
+)
+
+// AddActionVersion 注册 Action 的指定版本, 请求参数 Version(或请求头 X-Api-Version)选择版本,
+// responseType 为响应结构体(或其指针)样例, 用于生成 OpenAPI 文档, 可以为 nil
+func AddActionVersion(actionID string, version string, newRequesterParameter HTTPRequestParameter, handleFunc HTTPHandleFunc, responseType interface{}) {
+	addActionVersionEntry(actionID, version, httpHandleEntry{
+		handleFunc:            handleFunc,
+		newRequesterParameter: newRequesterParameter,
+		logResponse:           defaultLogResponse,
+		logger:                defaultAPILogger,
+		responseType:          reflect.TypeOf(responseType),
+	})
+}
+
+// HandleActionVersion 类型安全的 Action 版本注册, 参见 Handle 及 AddActionVersion
+func HandleActionVersion[Req, Resp any](actionID string, version string, fn func(c *gin.Context, req *Req) (Resp, error), options ...HandleOptionFunc) {
+	h, o := newHandleEntry(fn, options...)
+	addActionVersionEntry(actionID, version, h)
+	if o.unHtmlEscape {
+		unHtmlEscapeAction[actionID] = "1"
+	}
+}
+
+func addActionVersionEntry(actionID string, version string, h httpHandleEntry) {
+	syncActionVersion.Lock()
+	defer syncActionVersion.Unlock()
+	if _, ok := httpActionVersionEntry[actionID]; !ok {
+		httpActionVersionEntry[actionID] = make(map[string]httpHandleEntry)
+	}
+	httpActionVersionEntry[actionID][version] = h
+}
+
+// SetActionDefaultVersion 设置请求未指定版本时使用的版本, 未设置时使用 AddHTTPHandle 等注册的无版本处理程序(不存在时应答版本不支持)
+func SetActionDefaultVersion(actionID string, version string) {
+	syncActionVersion.Lock()
+	defer syncActionVersion.Unlock()
+	actionDefaultVersion[actionID] = version
+}
+
+// SetActionDeprecation 设置 Action 版本的弃用信息, version 为空时对该 Action 未单独设置的所有版本生效, deprecation 为 nil 时取消
+func SetActionDeprecation(actionID string, version string, deprecation *ActionDeprecation) {
+	syncActionVersion.Lock()
+	defer syncActionVersion.Unlock()
+	if deprecation == nil {
+		delete(actionDeprecation, actionID+"\n"+version)
+		return
+	}
+	actionDeprecation[actionID+"\n"+version] = deprecation
+}
+
+// SetActionVersionHeader 设置选择版本的请求头, 默认 X-Api-Version, 请求参数 Version 优先
+func SetActionVersionHeader(header string) {
+	actionVersionHeader = header
+}
+
+// SetActionVersionNotFoundResponse 设置请求的版本未注册时的应答内容, 缺省 {"Code":161,"Message":"Unsupported Action Version"}
+func SetActionVersionNotFoundResponse(response gin.H) {
+	actionVersionNotFoundRsp = response
+}
+
+// GetActionVersion 返回当前请求使用的 Action 版本, 无版本处理程序时为空
+func GetActionVersion(c *gin.Context) string {
+	return c.GetString(actionVersionContextKey)
+}
+
+// getActionEntry 按请求参数 Version 或版本请求头查找 Action 处理程序, 参见 resolveActionEntry
+func getActionEntry(c *gin.Context, p *httpRequestActionParam) (*httpHandleEntry, string, bool) {
+	version := p.Version
+	if version == "" && actionVersionHeader != "" {
+		version = c.GetHeader(actionVersionHeader)
+	}
+	return resolveActionEntry(p.Action, version)
+}
+
+// resolveActionEntry 查找 Action 指定版本(为空时为默认版本)的处理程序, 返回实际使用的版本; Action 已注册但版本不存在时返回 nil, true
+func resolveActionEntry(action string, version string) (*httpHandleEntry, string, bool) {
+	syncActionVersion.RLock()
+	defer syncActionVersion.RUnlock()
+	versions := httpActionVersionEntry[action]
+	if version == "" {
+		version = actionDefaultVersion[action]
+	}
+	if h, ok := versions[version]; ok && version != "" {
+		return &h, version, true
+	}
+	h, ok := httpActionEntry[action]
+	switch {
+	case ok && (version == "" || len(versions) == 0):
+		return &h, "", true
+	case ok || len(versions) > 0:
+		return nil, version, true
+	}
+	return nil, "", false
+}
+
+// markActionDeprecation 记录请求使用的版本, 已弃用时输出 Deprecation/Sunset/Link 应答头并计数
+func markActionDeprecation(c *gin.Context, action string, version string) {
+	c.Set(actionVersionContextKey, version)
+	syncActionVersion.RLock()
+	d, ok := actionDeprecation[action+"\n"+version]
+	if !ok {
+		d, ok = actionDeprecation[action+"\n"]
+	}
+	syncActionVersion.RUnlock()
+	if !ok {
+		return
+	}
+	if d.Since.IsZero() {
+		c.Header("Deprecation", "true")
+	} else {
+		c.Header("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+	}
+	if !d.Sunset.IsZero() {
+		c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		c.Header("Link", "<"+d.Link+">; rel=\"deprecation\"")
+	}
+	prometheus.UpdateDeprecatedMetric(action, version)
+}
+
+// getActionVersions 返回 Action 已注册的版本(升序), 用于生成 OpenAPI 文档
+func getActionVersions(action string) []string {
+	syncActionVersion.RLock()
+	defer syncActionVersion.RUnlock()
+	var versions []string
+	for v := range httpActionVersionEntry[action] {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// isActionDeprecated Action 版本是否设置了弃用信息
+func isActionDeprecated(action string, version string) bool {
+	syncActionVersion.RLock()
+	defer syncActionVersion.RUnlock()
+	if _, ok := actionDeprecation[action+"\n"+version]; ok {
+		return true
+	}
+	_, ok := actionDeprecation[action+"\n"]
+	return ok
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/util"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAdminAPI 注册运行时管理接口, 不受 ServiceDisabled 影响.
+// checkACL 为空时仅允许本机(loopback)访问, 经同机反向代理转发的请求也来自 loopback, 注册在对外服务的路由上时必须设置 checkACL
+//
+//	GET    /service/ready                   服务状态
+//	POST   /service/ready?ready=false       api.SetServiceReady
+//	GET    /service/exclude                 ExcludeInitServiceDisabled 列表
+//	POST   /service/exclude?key=xxx         添加 ExcludeInitServiceDisabled
+//	DELETE /service/exclude?key=xxx         删除 ExcludeInitServiceDisabled
+//	GET    /log                             category Info日志选项及 logger 日志级别
+//	POST   /log/category?category=SQL&enable=false
+//	POST   /log/level?logger=API&level=DEBUG
+//	GET    /cron                            定时任务列表
+//	POST   /cron/:name/pause|resume|trigger 暂停/恢复/立即执行定时任务
+//	GET    /keepalive                       keepalive 状态(JSON)
+//	GET    /goroutines?debug=2              goroutine dump
+func RegisterAdminAPI(r gin.IRouter, checkACL HTTPCheckACL) {
+	g := r.Group("", adminACL(checkACL))
+	g.GET("/service/ready", adminGetServiceReady)
+	g.POST("/service/ready", adminSetServiceReady)
+	g.GET("/service/exclude", adminGetExcludeServiceDisabled)
+	g.POST("/service/exclude", adminAddExcludeServiceDisabled)
+	g.DELETE("/service/exclude", adminRemoveExcludeServiceDisabled)
+	g.GET("/log", adminGetLogOptions)
+	g.POST("/log/category", adminSetLogCategory)
+	g.POST("/log/level", adminSetLoggerLevel)
+	g.GET("/cron", adminGetCronJobs)
+	g.POST("/cron/:name/:op", adminOperateCronJob)
+	g.GET("/keepalive", data.OutputKeepaliveJSON)
+	g.GET("/goroutines", adminDumpGoroutines)
+}
+
+// adminACL 管理接口权限检查, checkACL 为空时仅允许 loopback 地址访问
+func adminACL(checkACL HTTPCheckACL) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checkACL == nil {
+			if ip := net.ParseIP(c.RemoteIP()); ip == nil || !ip.IsLoopback() {
+				c.AbortWithStatusJSON(http.StatusForbidden, defaultAccessNoRightResponse)
+			}
+			return
+		}
+		switch checkACL(c.Request.URL.Path, "", c) {
+		case HTTPAclOK:
+		case HTTPAclDeny:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, defaultAccessDenyResponse)
+		default:
+			c.AbortWithStatusJSON(http.StatusForbidden, defaultAccessNoRightResponse)
+		}
+	}
+}
+
+// adminParam 参数可以通过 query 或 form 传递
+func adminParam(c *gin.Context, key string) string {
+	if v, ok := c.GetQuery(key); ok {
+		return v
+	}
+	return c.PostForm(key)
+}
+
+func adminResponse(c *gin.Context, d interface{}) {
+	c.JSON(http.StatusOK, gin.H{"Code": 0, "Message": "ok", "Data": d})
+}
+
+func adminError(c *gin.Context, httpCode int, err string) {
+	c.JSON(httpCode, gin.H{"Code": httpCode, "Message": err})
+}
+
+func adminGetServiceReady(c *gin.Context) {
+	adminResponse(c, gin.H{"Ready": IsServiceReady(), "ShuttingDown": IsServiceShuttingDown(), "InFlight": data.GetInFlight()})
+}
+
+func adminSetServiceReady(c *gin.Context) {
+	ready, err := strconv.ParseBool(adminParam(c, "ready"))
+	if err != nil {
+		adminError(c, http.StatusBadRequest, "invalid parameter ready")
+		return
+	}
+	log.Info("[AdminAPI] [%s] SetServiceReady:%v", c.ClientIP(), ready)
+	SetServiceReady(ready)
+	adminGetServiceReady(c)
+}
+
+func adminGetExcludeServiceDisabled(c *gin.Context) {
+	adminResponse(c, GetExcludeServiceDisabled())
+}
+
+func adminAddExcludeServiceDisabled(c *gin.Context) {
+	key := adminParam(c, "key")
+	if key == "" {
+		adminError(c, http.StatusBadRequest, "missing parameter key")
+		return
+	}
+	log.Info("[AdminAPI] [%s] AddExcludeServiceDisabled:%s", c.ClientIP(), key)
+	AddExcludeServiceDisabled(key)
+	adminGetExcludeServiceDisabled(c)
+}
+
+func adminRemoveExcludeServiceDisabled(c *gin.Context) {
+	key := adminParam(c, "key")
+	if key == "" {
+		adminError(c, http.StatusBadRequest, "missing parameter key")
+		return
+	}
+	log.Info("[AdminAPI] [%s] RemoveExcludeServiceDisabled:%s", c.ClientIP(), key)
+	RemoveExcludeServiceDisabled(key)
+	adminGetExcludeServiceDisabled(c)
+}
+
+func adminGetLogOptions(c *gin.Context) {
+	adminResponse(c, gin.H{"Categories": log.GetCategoryInfoLogOptions(), "Loggers": log.GetLoggerLevels()})
+}
+
+func adminSetLogCategory(c *gin.Context) {
+	category := adminParam(c, "category")
+	enable, err := strconv.ParseBool(adminParam(c, "enable"))
+	if category == "" || err != nil {
+		adminError(c, http.StatusBadRequest, "invalid parameter category or enable")
+		return
+	}
+	log.Info("[AdminAPI] [%s] SetCategoryInfoLogOption:%s %v", c.ClientIP(), category, enable)
+	log.SetCategoryInfoLogOption(category, enable)
+	adminGetLogOptions(c)
+}
+
+func adminSetLoggerLevel(c *gin.Context) {
+	logger, level := adminParam(c, "logger"), adminParam(c, "level")
+	log.Info("[AdminAPI] [%s] SetLoggerLevel:%s %s", c.ClientIP(), logger, level)
+	if err := log.SetLoggerLevel(logger, level); err != nil {
+		adminError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	adminGetLogOptions(c)
+}
+
+func adminGetCronJobs(c *gin.Context) {
+	adminResponse(c, util.GetCronJobs())
+}
+
+func adminOperateCronJob(c *gin.Context) {
+	name, op := c.Param("name"), c.Param("op")
+	var f func(string) error
+	switch op {
+	case "pause":
+		f = util.PauseCronJob
+	case "resume":
+		f = util.ResumeCronJob
+	case "trigger":
+		f = util.TriggerCronJob
+	default:
+		adminError(c, http.StatusNotFound, "unknown operation "+op)
+		return
+	}
+	log.Info("[AdminAPI] [%s] cron job %s %s", c.ClientIP(), name, op)
+	if err := f(name); err != nil {
+		adminError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	adminGetCronJobs(c)
+}
+
+func adminDumpGoroutines(c *gin.Context) {
+	debug, err := strconv.Atoi(c.DefaultQuery("debug", "2"))
+	if err != nil {
+		debug = 2
+	}
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Status(http.StatusOK)
+	_ = pprof.Lookup("goroutine").WriteTo(c.Writer, debug)
+}
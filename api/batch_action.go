@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/prometheus"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	//BatchActionResult 批量请求中单个 Action 的处理结果, 顺序与请求一致
+	BatchActionResult struct {
+		Status   int             //HTTP 状态码
+		Response json.RawMessage //Action 应答内容
+	}
+	//_BatchResponseWriter 收集单个 Action 的应答
+	_BatchResponseWriter struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+)
+
+var (
+	batchActionMaxItems   = 50
+	batchActionParallel   = 1
+	batchActionMaxBytes   = int64(1 << 20)
+	batchActionInvalidRsp = gin.H{"Code": 162, "Message": "Invalid Batch Request"}
+	batchActionTooManyRsp = gin.H{"Code": 163, "Message": "Too Many Batch Items"}
+	batchActionTooLarge   = gin.H{"Code": 164, "Message": "Batch Request Too Large"}
+)
+
+// SetBatchActionLimit 设置批量请求最大 Action 数(默认 50)及并行数(默认 1, 顺序执行)
+func SetBatchActionLimit(maxItems int, parallel int) {
+	if maxItems > 0 {
+		batchActionMaxItems = maxItems
+	}
+	if parallel > 0 {
+		batchActionParallel = parallel
+	}
+}
+
+// SetBatchActionMaxBytes 设置批量请求体最大字节数(默认 1MB), 超过时返回 413
+func SetBatchActionMaxBytes(maxBytes int64) {
+	if maxBytes > 0 {
+		batchActionMaxBytes = maxBytes
+	}
+}
+
+// RegisterBatchActionHandle 注册批量 Action 接口, 请求体为 JSON 数组, 每项为一个 Action 请求(如 {"Action":"GetUser","Id":1});
+// 每项作为 POST / 请求重新进入 r, 与单独请求相同地执行 ACL, 参数绑定, ServiceDisabled 检查, 处理程序, 监控, 审核日志及指标,
+// 应答 {"Code":0,"Results":[{"Status":200,"Response":{...}}]}
+func RegisterBatchActionHandle(r *gin.Engine, path string) {
+	r.POST(path, func(c *gin.Context) { batchActionHandle(r, c) })
+	r.OPTIONS(path, httpPreflightHandle)
+}
+
+func batchActionHandle(r *gin.Engine, c *gin.Context) {
+	start := time.Now()
+	requestID := prepareRequestID(c)
+	urlPath := c.Request.URL.Path
+	addAccessControlAllowHeader(c, urlPath, "")
+	var items []json.RawMessage
+	limit := batchActionMaxBytes
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, limit))
+	tooLarge := err != nil && int64(len(body)) >= limit
+	if err == nil {
+		err = json.Unmarshal(body, &items)
+	}
+	var response interface{}
+	var actions []string
+	var results []BatchActionResult
+	httpCode := http.StatusOK
+	switch {
+	case tooLarge:
+		httpCode, response = http.StatusRequestEntityTooLarge, batchActionTooLarge
+	case err != nil || len(items) == 0:
+		httpCode, response = http.StatusBadRequest, batchActionInvalidRsp
+	case len(items) > batchActionMaxItems:
+		httpCode, response = http.StatusRequestEntityTooLarge, batchActionTooManyRsp
+	default:
+		results = make([]BatchActionResult, len(items))
+		actions = make([]string, len(items))
+		sem := make(chan struct{}, batchActionParallel)
+		wg := sync.WaitGroup{}
+		for i, item := range items {
+			p := httpRequestActionParam{}
+			_ = json.Unmarshal(item, &p)
+			actions[i] = p.Action
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(i int, item json.RawMessage) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				results[i] = serveBatchAction(r, c, requestID, item)
+			}(i, item)
+		}
+		wg.Wait()
+		response = gin.H{"Code": 0, "Results": results}
+	}
+	c.JSON(httpCode, response)
+	strCustomLogTag := ""
+	if customAPILogTag && httpCustomLogTag != nil {
+		strCustomLogTag = httpCustomLogTag(c)
+	}
+	strResponse := fmt.Sprintf(`{"Code":%d}`, getCodeFromInterface(response))
+	if results != nil {
+		status := make([]string, len(results))
+		for i, result := range results {
+			status[i] = fmt.Sprintf("%d", result.Status)
+		}
+		strResponse = fmt.Sprintf(`{"Code":0,"Status":[%s]}`, strings.Join(status, ","))
+	}
+	strActions, _ := json.Marshal(actions)
+	log.Info2(defaultAPILogger, "[%s]\t[%s]\t%s\tRequest:{\"Actions\":%s}\tResponse:%s\tRequestId:%s", urlPath, time.Since(start), strCustomLogTag, strActions, strResponse, requestID)
+	extraLabelValues := prometheus.GetExtraLabelValue("", urlPath, c.Request, response, c)
+	prometheus.UpdateApiMetric(getCodeFromInterface(response), "", start, c.Request, urlPath, extraLabelValues)
+}
+
+// serveBatchAction 以原请求的请求头(不含 Idempotency-Key), 客户端地址及 request id 构造 POST / 请求执行单个 Action
+func serveBatchAction(r *gin.Engine, c *gin.Context, requestID string, item json.RawMessage) BatchActionResult {
+	trimmed := bytes.TrimSpace(item)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		b, _ := json.Marshal(batchActionInvalidRsp)
+		return BatchActionResult{Status: http.StatusBadRequest, Response: b}
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, "/", bytes.NewReader(trimmed))
+	if err != nil {
+		b, _ := json.Marshal(batchActionInvalidRsp)
+		return BatchActionResult{Status: http.StatusBadRequest, Response: b}
+	}
+	req.Header = c.Request.Header.Clone()
+	req.Header.Del(IdempotencyKeyHeader)
+	req.Header.Del("Content-Length")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(data.RequestIDHeader, requestID)
+	req.RemoteAddr = c.Request.RemoteAddr
+	req.Host = c.Request.Host
+	w := &_BatchResponseWriter{header: make(http.Header)}
+	r.ServeHTTP(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	rsp := bytes.TrimSpace(w.body.Bytes())
+	if len(rsp) == 0 {
+		rsp = []byte("null")
+	} else if !json.Valid(rsp) {
+		rsp, _ = json.Marshal(string(rsp))
+	}
+	return BatchActionResult{Status: w.status, Response: rsp}
+}
+
+func (w *_BatchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *_BatchResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *_BatchResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+}
+
+func (w *_BatchResponseWriter) Flush() {}
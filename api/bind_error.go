@@ -0,0 +1,229 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	// BindFieldError 参数绑定/校验失败的字段
+	BindFieldError struct {
+		Field   string      //请求参数名, query/form 为 form tag, json 为 json tag, 嵌套字段以 . 连接
+		Rule    string      //校验规则 required,min,oneof...; 类型转换失败为 type
+		Param   string      `json:",omitempty"` //校验规则参数, 如 min=1 中的 1
+		Value   interface{} //收到的值
+		Source  string      //参数来源 query,form,json,path
+		Message string      //错误描述, 由 SetBindErrorTranslator 设置的函数生成
+	}
+	// BindErrorTranslator 生成字段错误描述, 可根据 c 中的 Accept-Language 等信息翻译
+	BindErrorTranslator func(c *gin.Context, e BindFieldError) string
+	// BindErrorResponse 构造参数绑定失败的应答内容
+	BindErrorResponse func(c *gin.Context, errs []BindFieldError, bindError error) interface{}
+	bindValueError    struct {
+		Field  string
+		Value  string
+		Rule   string //为空时为 type
+		Source string //为空时根据请求识别
+		Err    error
+	}
+)
+
+const (
+	//BindSourceQuery 参数来自 URL query
+	BindSourceQuery = "query"
+	//BindSourceForm 参数来自 POST form
+	BindSourceForm = "form"
+	//BindSourceJSON 参数来自 POST json
+	BindSourceJSON = "json"
+	//BindSourceXML 参数来自 POST xml
+	BindSourceXML = "xml"
+	//BindSourceMsgPack 参数来自 POST msgpack
+	BindSourceMsgPack = "msgpack"
+	//BindSourceProtoBuf 参数来自 POST protobuf
+	BindSourceProtoBuf = "protobuf"
+	//BindSourcePath 参数来自 RESTFul url 路径段
+	BindSourcePath = "path"
+)
+
+var (
+	bindErrorTranslator = defaultBindErrorTranslator
+	bindErrorResponse   = defaultBindFieldErrorResponse
+	bindRuleMessages    = map[string]string{
+		"required": "%s is required",
+		"type":     "%s has invalid type",
+		"min":      "%s must be at least %s",
+		"gte":      "%s must be greater than or equal to %s",
+		"gt":       "%s must be greater than %s",
+		"max":      "%s must be at most %s",
+		"lte":      "%s must be less than or equal to %s",
+		"lt":       "%s must be less than %s",
+		"len":      "%s length must be %s",
+		"oneof":    "%s must be one of [%s]",
+		"email":    "%s must be a valid email",
+		"syntax":   "%s is malformed",
+	}
+)
+
+func (e *bindValueError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *bindValueError) Unwrap() error {
+	return e.Err
+}
+
+// SetBindErrorTranslator 设置字段错误描述生成函数
+func SetBindErrorTranslator(translator BindErrorTranslator) {
+	if translator == nil {
+		translator = defaultBindErrorTranslator
+	}
+	bindErrorTranslator = translator
+}
+
+// SetBindErrorResponse 设置参数绑定失败应答构造函数, 缺省应答 {"Code":230,"Message":"Bind params error [...]","Errors":[...]};
+// SetDefaultBindError/SetDefaultRestfulBindError 设置的固定应答优先
+func SetBindErrorResponse(response BindErrorResponse) {
+	if response == nil {
+		response = defaultBindFieldErrorResponse
+	}
+	bindErrorResponse = response
+}
+
+// ParseBindError 将参数绑定错误转换为字段错误列表, param 为绑定的参数(指针)
+func ParseBindError(c *gin.Context, param interface{}, bindError error) []BindFieldError {
+	source := getBindSource(c)
+	var errs []BindFieldError
+	var validationErrors validator.ValidationErrors
+	var valueError *bindValueError
+	var typeError *json.UnmarshalTypeError
+	var syntaxError *json.SyntaxError
+	switch {
+	case errors.As(bindError, &validationErrors):
+		t := reflect.TypeOf(param)
+		for _, fe := range validationErrors {
+			errs = append(errs, BindFieldError{
+				Field:  bindFieldName(t, fe.StructNamespace(), source),
+				Rule:   fe.Tag(),
+				Param:  fe.Param(),
+				Value:  fe.Value(),
+				Source: source,
+			})
+		}
+	case errors.As(bindError, &valueError):
+		e := BindFieldError{Field: valueError.Field, Rule: valueError.Rule, Value: valueError.Value, Source: valueError.Source}
+		if e.Rule == "" {
+			e.Rule = "type"
+		}
+		if e.Source == "" {
+			e.Source = source
+		}
+		errs = append(errs, e)
+	case errors.As(bindError, &typeError):
+		errs = append(errs, BindFieldError{Field: typeError.Field, Rule: "type", Value: typeError.Value, Source: BindSourceJSON})
+	case errors.As(bindError, &syntaxError):
+		errs = append(errs, BindFieldError{Rule: "syntax", Value: syntaxError.Offset, Source: BindSourceJSON})
+	}
+	for i := range errs {
+		errs[i].Message = bindErrorTranslator(c, errs[i])
+	}
+	return errs
+}
+
+func newBindErrorResponse(c *gin.Context, param interface{}, bindError error) interface{} {
+	return bindErrorResponse(c, ParseBindError(c, param, bindError), bindError)
+}
+
+func defaultBindFieldErrorResponse(_ *gin.Context, errs []BindFieldError, bindError error) interface{} {
+	if errs == nil {
+		errs = []BindFieldError{}
+	}
+	return gin.H{"Code": 230, "Message": fmt.Sprintf("Bind params error [%v]", bindError), "Errors": errs}
+}
+
+func defaultBindErrorTranslator(_ *gin.Context, e BindFieldError) string {
+	field := e.Field
+	if field == "" {
+		field = "request"
+	}
+	if format, ok := bindRuleMessages[e.Rule]; ok {
+		if strings.Count(format, "%s") == 2 {
+			return fmt.Sprintf(format, field, e.Param)
+		}
+		return fmt.Sprintf(format, field)
+	}
+	return fmt.Sprintf("%s failed on the '%s' rule", field, e.Rule)
+}
+
+// getBindSource GET 为 query, POST 按 Content-Type 识别 xml/msgpack/protobuf, Content-Type 为 json 或 body 以 { 或 [ 开头为 json, 否则为 form
+func getBindSource(c *gin.Context) string {
+	if c.Request.Method == "GET" {
+		return BindSourceQuery
+	}
+	switch c.ContentType() {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return BindSourceXML
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		return BindSourceMsgPack
+	case binding.MIMEPROTOBUF, "application/protobuf":
+		return BindSourceProtoBuf
+	}
+	if cb, ok := c.Get(requestRawParams); ok {
+		if cbb, ok := cb.([]byte); ok && isJSONBody(c, cbb) {
+			return BindSourceJSON
+		}
+	}
+	return BindSourceForm
+}
+
+// bindFieldName 将 validator 的 struct namespace(Req.Base.Name) 转换为请求参数名
+func bindFieldName(t reflect.Type, namespace string, source string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	var names []string
+	for _, part := range parts {
+		index := ""
+		if i := strings.Index(part, "["); i >= 0 {
+			part, index = part[:i], part[i:]
+		}
+		for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct {
+			names = append(names, part+index)
+			continue
+		}
+		f, ok := t.FieldByName(part)
+		if !ok {
+			names = append(names, part+index)
+			continue
+		}
+		t = f.Type
+		tagName := "form"
+		switch source {
+		case BindSourceJSON, BindSourceProtoBuf:
+			tagName = "json"
+		case BindSourceXML:
+			tagName = "xml"
+		case BindSourceMsgPack:
+			tagName = "codec"
+		}
+		name := strings.Split(f.Tag.Get(tagName), ",")[0]
+		if name == "" || name == "-" {
+			if f.Anonymous {
+				continue
+			}
+			name = f.Name
+		}
+		names = append(names, name+index)
+	}
+	return strings.Join(names, ".")
+}
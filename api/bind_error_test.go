@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseBindErrorJSONValidation(t *testing.T) {
+	type request struct {
+		Age  int    `json:"Age" binding:"min=0"`
+		Note string `json:"Note"`
+	}
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Age":-1,"Note":"100%"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	prepareRequestParam(c, true)
+	param := &request{}
+	err := bindPost(param, c, false)
+	if err == nil {
+		t.Fatal("expect bind error")
+	}
+	errs := ParseBindError(c, param, err)
+	if len(errs) != 1 {
+		t.Fatalf("expect 1 field error, got %+v", errs)
+	}
+	e := errs[0]
+	if e.Field != "Age" || e.Rule != "min" || e.Param != "0" || e.Source != BindSourceJSON || e.Value != -1 {
+		t.Fatalf("unexpected field error %+v", e)
+	}
+}
@@ -50,14 +50,14 @@ func bindQuery(obj interface{}, c *gin.Context) error {
 func bindPost(obj interface{}, c *gin.Context, isBindingComplex bool) error {
 	if cb, ok := c.Get(requestRawParams); ok {
 		if cbb, ok := cb.([]byte); ok {
-			if isBindingComplex {
-				if err := data.JSONUnmarshal(cbb, obj); err == nil {
-					return nil
-				}
-			} else {
-				if err := binding.JSON.BindBody(cbb, obj); err == nil {
-					return nil
+			if negotiated, err := bindPostNegotiated(obj, c, cbb); negotiated {
+				return err
+			}
+			if isJSONBody(c, cbb) { //json 请求直接返回 json 绑定/校验错误, 不再按 form 解析
+				if isBindingComplex {
+					return data.JSONUnmarshal(cbb, obj)
 				}
+				return binding.JSON.BindBody(cbb, obj)
 			}
 			vs, e := url.ParseQuery(string(cbb))
 			if e != nil {
@@ -73,6 +73,15 @@ func bindPost(obj interface{}, c *gin.Context, isBindingComplex bool) error {
 	return fmt.Errorf("cannot retreive post parameters")
 }
 
+// isJSONBody Content-Type 为 json 或 body 以 { 或 [ 开头
+func isJSONBody(c *gin.Context, body []byte) bool {
+	if c.ContentType() == binding.MIMEJSON {
+		return true
+	}
+	body = bytes.TrimSpace(body)
+	return len(body) > 0 && (body[0] == '{' || body[0] == '[')
+}
+
 func mergeArray(u url.Values) map[string][]string {
 	o := make(map[string][]string)
 	for k, v := range u {
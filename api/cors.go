@@ -0,0 +1,185 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// CORSPolicy 跨域访问策略
+	CORSPolicy struct {
+		AllowOrigins     []string         //允许的 Origin, 支持完全匹配(https://a.com), 子域名通配(https://*.a.com) 及 *
+		AllowOriginRegex []*regexp.Regexp //允许的 Origin 正则
+		AllowMethods     []string         //允许的方法, 为空时 GET, POST, OPTIONS, PUT, DELETE
+		AllowHeaders     []string         //允许的请求头, 为空时回显 Access-Control-Request-Headers
+		ExposeHeaders    []string         //允许浏览器读取的应答头
+		MaxAge           int              //preflight 结果缓存秒数, 0 不设置
+		AllowCredentials bool             //是否允许携带 cookie 等凭证
+	}
+)
+
+var (
+	defaultCORSAllowMethods = []string{"GET", "POST", "OPTIONS", "PUT", "DELETE"}
+	globalCORSPolicy        *CORSPolicy
+	urlCORSPolicy           = make(map[string]*CORSPolicy) //key: url path(RESTFul 为注册的 url 模板)
+	actionCORSPolicy        = make(map[string]*CORSPolicy) //key: action
+	syncCORSPolicy          = sync.RWMutex{}
+)
+
+// SetCORSPolicy 设置全局跨域策略, 未设置任何策略时沿用回显 Origin 的旧行为(受 SetHTTPAccessControlAllowOrigin 控制)
+func SetCORSPolicy(policy *CORSPolicy) {
+	syncCORSPolicy.Lock()
+	defer syncCORSPolicy.Unlock()
+	globalCORSPolicy = policy
+}
+
+// SetURLCORSPolicy 设置 URL 跨域策略, 优先于全局策略, RESTFul 入口使用注册时的 url 模板(如 /user/:id)
+func SetURLCORSPolicy(urlPath string, policy *CORSPolicy) {
+	syncCORSPolicy.Lock()
+	defer syncCORSPolicy.Unlock()
+	urlCORSPolicy[urlPath] = policy
+}
+
+// SetActionCORSPolicy 设置 Action 跨域策略, 优先于 URL 及全局策略; preflight 请求仅能从 query 参数 Action 识别
+func SetActionCORSPolicy(action string, policy *CORSPolicy) {
+	syncCORSPolicy.Lock()
+	defer syncCORSPolicy.Unlock()
+	actionCORSPolicy[action] = policy
+}
+
+// IsOriginAllowed Origin 是否被策略允许
+func (p *CORSPolicy) IsOriginAllowed(origin string) bool {
+	for _, o := range p.AllowOrigins {
+		switch {
+		case o == "*" || strings.EqualFold(o, origin):
+			return true
+		case strings.Contains(o, "://*."):
+			i := strings.Index(o, "*")
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+				return true
+			}
+		}
+	}
+	for _, r := range p.AllowOriginRegex {
+		if r.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *CORSPolicy) isAllowAll() bool {
+	for _, o := range p.AllowOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// getCORSPolicy 依次查找 Action, URL, 全局策略, 未设置任何策略返回 nil
+func getCORSPolicy(urlPath, action string) *CORSPolicy {
+	syncCORSPolicy.RLock()
+	defer syncCORSPolicy.RUnlock()
+	if p, ok := actionCORSPolicy[action]; ok && action != "" {
+		return p
+	}
+	if p, ok := urlCORSPolicy[urlPath]; ok {
+		return p
+	}
+	return globalCORSPolicy
+}
+
+// setCORSOriginHeader 设置 Allow-Origin/Credentials/Expose 头, 返回 Origin 是否被允许
+func setCORSOriginHeader(c *gin.Context, p *CORSPolicy, origin string) bool {
+	c.Writer.Header().Add("Vary", "Origin")
+	if !p.IsOriginAllowed(origin) {
+		return false
+	}
+	if p.isAllowAll() && !p.AllowCredentials {
+		c.Header("Access-Control-Allow-Origin", "*")
+	} else {
+		c.Header("Access-Control-Allow-Origin", origin)
+	}
+	if p.AllowCredentials {
+		c.Header("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.ExposeHeaders) > 0 {
+		c.Header("Access-Control-Expose-Headers", strings.Join(p.ExposeHeaders, ", "))
+	}
+	return true
+}
+
+func addAccessControlAllowHeader(c *gin.Context, urlPath, action string) {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		return
+	}
+	if p := getCORSPolicy(urlPath, action); p != nil {
+		setCORSOriginHeader(c, p, origin)
+		return
+	}
+	if httpAccessControlAllowOrigin {
+		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		c.Header("Access-Control-Allow-Origin", origin)
+	}
+}
+
+func isCORSPreflight(c *gin.Context) bool {
+	return c.Request.Method == http.MethodOptions && c.GetHeader("Origin") != "" && c.GetHeader("Access-Control-Request-Method") != ""
+}
+
+// corsPreflight 应答 OPTIONS 请求, 有策略时按策略应答 204(Origin/方法不允许时 403), 否则沿用旧行为
+func corsPreflight(c *gin.Context, urlPath, action string) {
+	origin := c.GetHeader("Origin")
+	p := getCORSPolicy(urlPath, action)
+	if p == nil || origin == "" {
+		if origin != "" {
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Headers", "Content-Type")
+		}
+		c.JSON(http.StatusOK, gin.H{"Code": 0, "Message": "options success"})
+		return
+	}
+	methods := p.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSAllowMethods
+	}
+	requestMethod := c.GetHeader("Access-Control-Request-Method")
+	methodAllowed := false
+	for _, m := range methods {
+		if strings.EqualFold(m, requestMethod) {
+			methodAllowed = true
+			break
+		}
+	}
+	if !methodAllowed || !setCORSOriginHeader(c, p, origin) {
+		c.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	c.Header("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(p.AllowHeaders) > 0 {
+		c.Header("Access-Control-Allow-Headers", strings.Join(p.AllowHeaders, ", "))
+	} else if h := c.GetHeader("Access-Control-Request-Headers"); h != "" {
+		c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		c.Header("Access-Control-Allow-Headers", h)
+	}
+	if p.MaxAge > 0 {
+		c.Header("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// httpPreflightHandle 已注册 URL 的 OPTIONS 入口
+func httpPreflightHandle(c *gin.Context) {
+	corsPreflight(c, c.Request.URL.Path, c.Query("Action"))
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var (
+	grpcHealthServer  *health.Server
+	grpcServiceHealth = make(map[string]bool) //key: gRPC service name value: serving
+	syncGRPCHealth    = sync.Mutex{}
+)
+
+// RegisterGRPCHealthServer 注册 grpc_health_v1 服务.
+// 整体状态("")跟随 SetServiceReady 及优雅停止阶段; 各服务状态由 SetGRPCServiceHealth 设置, 整体不可用时均为 NOT_SERVING
+func RegisterGRPCHealthServer(s *grpc.Server) {
+	syncGRPCHealth.Lock()
+	grpcHealthServer = health.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, grpcHealthServer)
+	syncGRPCHealth.Unlock()
+	refreshGRPCHealth()
+}
+
+// SetGRPCServiceHealth 设置 gRPC 服务(如 package.Service)的健康状态
+func SetGRPCServiceHealth(service string, serving bool) {
+	syncGRPCHealth.Lock()
+	grpcServiceHealth[service] = serving
+	syncGRPCHealth.Unlock()
+	refreshGRPCHealth()
+}
+
+func refreshGRPCHealth() {
+	syncGRPCHealth.Lock()
+	defer syncGRPCHealth.Unlock()
+	if grpcHealthServer == nil {
+		return
+	}
+	ready := IsServiceReady() && !IsServiceShuttingDown()
+	grpcHealthServer.SetServingStatus("", grpcServingStatus(ready))
+	for service, serving := range grpcServiceHealth {
+		grpcHealthServer.SetServingStatus(service, grpcServingStatus(ready && serving))
+	}
+}
+
+func grpcServingStatus(serving bool) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if serving {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type (
+	//GRPCCheckACL gRPC 权限访问控制，返回非0值，则拒绝访问
+	GRPCCheckACL func(ctx context.Context, fullMethod string) HTTPACLResult
+)
+
+var (
+	grpcCheckACL           GRPCCheckACL
+	defaultGRPCAPILogger   = "API"
+	grpcInternalServices   = []string{"/grpc.reflection.", "/grpc.health.v1."} //不受 ACL 及 ServiceDisabled 影响的内置服务
+	grpcStreamRequestLog   = "<stream>"
+	grpcServiceNotReady    = status.Error(codes.Unavailable, "Service Unavailable")
+	grpcAccessDenyError    = status.Error(codes.Unauthenticated, "请先登录")
+	grpcAccessNoRightError = status.Error(codes.PermissionDenied, "没有权限，请向管理员申请权限")
+)
+
+// SetGRPCCheckACL 设置 gRPC 权限检查函数, nil 不检查
+func SetGRPCCheckACL(checkACL GRPCCheckACL) {
+	grpcCheckACL = checkACL
+}
+
+// SetGRPCAPILogger 设置 gRPC 服务端日志 logger 名称
+func SetGRPCAPILogger(loggerName string) {
+	defaultGRPCAPILogger = loggerName
+}
+
+// GRPCUnaryServerInterceptors 内置 unary 拦截器: 日志及指标(含处理中请求计数), ACL, ServiceDisabled
+func GRPCUnaryServerInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{grpcUnaryLogMetric, grpcUnaryGuard}
+}
+
+// GRPCStreamServerInterceptors 内置 stream 拦截器: 日志及指标(含处理中请求计数), ACL, ServiceDisabled
+func GRPCStreamServerInterceptors() []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{grpcStreamLogMetric, grpcStreamGuard}
+}
+
+func grpcUnaryLogMetric(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (rsp interface{}, err error) {
+	data.InFlightAdd(data.InFlightGRPC)
+	defer data.InFlightDone(data.InFlightGRPC)
+	start := time.Now()
+	defer func() {
+		if e := recover(); e != nil {
+			log.Error2(defaultGRPCAPILogger, "[GRPC] [%s] panic:%v \nStack:%s", info.FullMethod, e, debug.Stack())
+			err = status.Errorf(codes.Internal, "panic: %v", e)
+		}
+		logGRPCCall(start, info.FullMethod, fmt.Sprintf("%v", req), rsp, err)
+		prometheus.UpdateGRPCMetric(int(status.Code(err)), grpcMethodName(info.FullMethod), start, info.FullMethod, prometheus.GetGRPCExtraLabelValue(info.FullMethod, req, rsp))
+	}()
+	return handler(grpcContextWithRequestID(ctx), req)
+}
+
+func grpcStreamLogMetric(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	data.InFlightAdd(data.InFlightGRPC)
+	defer data.InFlightDone(data.InFlightGRPC)
+	start := time.Now()
+	defer func() {
+		if e := recover(); e != nil {
+			log.Error2(defaultGRPCAPILogger, "[GRPC] [%s] panic:%v \nStack:%s", info.FullMethod, e, debug.Stack())
+			err = status.Errorf(codes.Internal, "panic: %v", e)
+		}
+		logGRPCCall(start, info.FullMethod, grpcStreamRequestLog, nil, err)
+		prometheus.UpdateGRPCMetric(int(status.Code(err)), grpcMethodName(info.FullMethod), start, info.FullMethod, prometheus.GetGRPCExtraLabelValue(info.FullMethod, nil, nil))
+	}()
+	return handler(srv, ss)
+}
+
+func grpcUnaryGuard(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := checkGRPCAccess(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func grpcStreamGuard(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkGRPCAccess(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// checkGRPCAccess 内置服务不检查; 先检查 ACL, 再检查 ServiceDisabled(ExcludeInitServiceDisabled 以 FullMethod 为 key)
+func checkGRPCAccess(ctx context.Context, fullMethod string) error {
+	for _, prefix := range grpcInternalServices {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return nil
+		}
+	}
+	if grpcCheckACL != nil {
+		switch grpcCheckACL(ctx, fullMethod) {
+		case HTTPAclDeny:
+			return grpcAccessDenyError
+		case HTTPAclNoRight:
+			return grpcAccessNoRightError
+		}
+	}
+	if _isCheckServiceNotReady("", fullMethod) {
+		return grpcServiceNotReady
+	}
+	return nil
+}
+
+// logGRPCCall 与 data.GRPCService 客户端日志格式一致, 可被 log_parser_grpc 解析
+func logGRPCCall(start time.Time, fullMethod string, request string, response interface{}, err error) {
+	strResponse := ""
+	if v := reflect.ValueOf(response); v.IsValid() && !(v.Kind() == reflect.Ptr && v.IsNil()) {
+		strResponse = defaultLogResponse(response)
+	}
+	if err == nil {
+		log.Info2(defaultGRPCAPILogger, "[GRPC]\t[%s]\t[%s]\tRequest:%s\tResponse:%s", time.Since(start), fullMethod, request, strResponse)
+	} else {
+		log.Error2(defaultGRPCAPILogger, "[GRPC]\t[%s]\t[%s]\tRequest:%s\tResponse:%s\tError:%v", time.Since(start), fullMethod, request, strResponse, err)
+	}
+}
+
+// grpcContextWithRequestID 使用 metadata x-request-id(无效时重新生成)作为 request id, 处理函数通过 data.RequestIDFromContext 获取
+func grpcContextWithRequestID(ctx context.Context) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(data.RequestIDMetadataKey); len(v) > 0 {
+			requestID = v[0]
+		}
+	}
+	if !data.IsValidRequestID(requestID) {
+		requestID = data.NewRequestID()
+	}
+	return data.ContextWithRequestID(ctx, requestID)
+}
+
+// grpcMethodName /package.Service/Method 返回 Method
+func grpcMethodName(fullMethod string) string {
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[i+1:]
+	}
+	return fullMethod
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/NeilXu2017/landau/log"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// HandleOptionFunc Handle 注册参数设置
+	HandleOptionFunc func(*_HandleOption)
+	_HandleOption    struct {
+		httpCodeStatus string
+		logResponse    HTTPLogResponse
+		logger         string
+		unHtmlEscape   bool
+	}
+	// APIError Handle 处理函数返回的业务错误, 以 Code/Message 应答
+	APIError struct {
+		Code    int
+		Message string
+	}
+)
+
+var (
+	defaultHandleErrorCode    = 500                     //Handle 处理函数返回非 APIError 错误时应答的 Code
+	defaultHandleErrorMessage = "Internal Server Error" //Handle 处理函数返回非 APIError 错误时应答的 Message, 错误内容仅记录日志
+)
+
+// NewAPIError 构建业务错误
+func NewAPIError(code int, format string, a ...interface{}) *APIError {
+	return &APIError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
+}
+
+// SetDefaultHandleErrorCode 设置 Handle 处理函数返回非 APIError 错误时应答的 Code, 默认 500
+func SetDefaultHandleErrorCode(code int) {
+	defaultHandleErrorCode = code
+}
+
+// SetDefaultHandleErrorMessage 设置 Handle 处理函数返回非 APIError 错误时应答的 Message, 默认 Internal Server Error
+func SetDefaultHandleErrorMessage(message string) {
+	defaultHandleErrorMessage = message
+}
+
+// SetHandleHTTPCodeStatus 设置从 response 中读取 HTTP 状态码的字段名
+func SetHandleHTTPCodeStatus(httpCodeStatus string) HandleOptionFunc {
+	return func(o *_HandleOption) {
+		o.httpCodeStatus = httpCodeStatus
+	}
+}
+
+// SetHandleLogger 设置日志 logger 及 response 日志内容
+func SetHandleLogger(loggerName string, logResponse HTTPLogResponse) HandleOptionFunc {
+	return func(o *_HandleOption) {
+		o.logger = loggerName
+		if logResponse != nil {
+			o.logResponse = logResponse
+		}
+	}
+}
+
+// SetHandleUnHtmlEscape 响应json内容不进行 HTML Escape 处理
+func SetHandleUnHtmlEscape() HandleOptionFunc {
+	return func(o *_HandleOption) {
+		o.unHtmlEscape = true
+	}
+}
+
+// Handle 类型安全的URL/Action处理程序注册, 请求参数由 Req 生成并绑定,
+// fn 返回 error 时应答 {"Code":..,"Message":..}, Code/Message 取自 APIError,
+// 否则记录错误日志, 应答 SetDefaultHandleErrorCode/SetDefaultHandleErrorMessage 设置值(不返回错误内容);
+// 请求日志内容为 Req 的 String() 或 JSON
+func Handle[Req, Resp any](urlPath string, actionID string, fn func(c *gin.Context, req *Req) (Resp, error), options ...HandleOptionFunc) {
+	h, o := newHandleEntry(fn, options...)
+	addHTTPHandleEntry(urlPath, actionID, h)
+	if o.unHtmlEscape {
+		if urlPath != "" && urlPath != "/" {
+			unHtmlEscapeURL[urlPath] = "1"
+		}
+		if actionID != "" {
+			unHtmlEscapeAction[actionID] = "1"
+		}
+	}
+}
+
+func newHandleEntry[Req, Resp any](fn func(c *gin.Context, req *Req) (Resp, error), options ...HandleOptionFunc) (httpHandleEntry, *_HandleOption) {
+	o := &_HandleOption{logResponse: defaultLogResponse, logger: defaultAPILogger}
+	for _, option := range options {
+		option(o)
+	}
+	responseType := reflect.TypeOf((*Resp)(nil)).Elem()
+	if responseType.Kind() == reflect.Interface {
+		responseType = nil
+	}
+	return httpHandleEntry{
+		handleFunc: func(c *gin.Context, param interface{}) (interface{}, string) {
+			req, ok := param.(*Req)
+			if !ok {
+				return gin.H{"Code": defaultHandleErrorCode, "Message": fmt.Sprintf("unexpected request param type %T", param)}, ""
+			}
+			rsp, err := fn(c, req)
+			if err != nil {
+				return handleErrorResponse(c, o.logger, err), handleRequestLog(req)
+			}
+			return rsp, handleRequestLog(req)
+		},
+		newRequesterParameter: func() interface{} { return new(Req) },
+		logResponse:           o.logResponse,
+		logger:                o.logger,
+		httpCodeStatus:        o.httpCodeStatus,
+		responseType:          responseType,
+	}, o
+}
+
+func handleErrorResponse(c *gin.Context, logger string, err error) gin.H {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return gin.H{"Code": apiErr.Code, "Message": apiErr.Message}
+	}
+	log.Error2(logger, "[%s]\t[Handle] error:%v\tRequestId:%s", c.Request.URL.Path, err, GetRequestID(c))
+	return gin.H{"Code": defaultHandleErrorCode, "Message": defaultHandleErrorMessage}
+}
+
+func handleRequestLog(req interface{}) string {
+	if v, ok := req.(fmt.Stringer); ok {
+		return v.String()
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Sprintf("%+v", req)
+	}
+	return string(b)
+}
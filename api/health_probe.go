@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	serviceShuttingDown int32 //是否处于优雅停止阶段
+)
+
+// SetServiceShuttingDown 设置服务是否处于优雅停止阶段, 期间 readiness 返回 503, gRPC health 返回 NOT_SERVING
+func SetServiceShuttingDown(shuttingDown bool) {
+	v := int32(0)
+	if shuttingDown {
+		v = 1
+	}
+	atomic.StoreInt32(&serviceShuttingDown, v)
+	refreshGRPCHealth()
+}
+
+// IsServiceShuttingDown 服务是否处于优雅停止阶段
+func IsServiceShuttingDown() bool {
+	return atomic.LoadInt32(&serviceShuttingDown) == 1
+}
+
+// LivenessHandle liveness 检查入口, 执行 data.RegisterLivenessChecker 注册的检查
+func LivenessHandle(c *gin.Context) {
+	rsp, ok := data.CheckLiveness(c.Request.Context())
+	writeHealthProbeResponse(c, rsp, ok)
+}
+
+// ReadinessHandle readiness 检查入口, 服务 Disable 或优雅停止阶段直接返回 503, 否则执行 data.RegisterReadinessChecker 注册的检查
+func ReadinessHandle(c *gin.Context) {
+	switch {
+	case IsServiceShuttingDown():
+		writeHealthProbeResponse(c, data.HealthProbeResponse{Status: data.HealthStatusDown, Reason: "shutting down"}, false)
+	case !IsServiceReady():
+		writeHealthProbeResponse(c, data.HealthProbeResponse{Status: data.HealthStatusDown, Reason: "service disabled"}, false)
+	default:
+		rsp, ok := data.CheckReadiness(c.Request.Context())
+		writeHealthProbeResponse(c, rsp, ok)
+	}
+}
+
+func writeHealthProbeResponse(c *gin.Context, rsp data.HealthProbeResponse, ok bool) {
+	httpCode := http.StatusOK
+	if !ok {
+		httpCode = http.StatusServiceUnavailable
+	}
+	c.JSON(httpCode, rsp)
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	//IdempotencyCaller 提取调用方标识(如 ACL/认证识别的用户), 与 Action/URL 及 Idempotency-Key 共同组成幂等 key; 返回空时不做幂等保护
+	IdempotencyCaller func(c *gin.Context) string
+	_IdempotencyCall  struct {
+		key         string
+		fingerprint string
+		completed   bool
+	}
+)
+
+const (
+	//IdempotencyKeyHeader 幂等 key 请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	//IdempotencyReplayedHeader 重放应答时设置的应答头
+	IdempotencyReplayedHeader = "Idempotency-Replayed"
+	idempotencyContextKey     = "landau_idempotency"
+	idempotencyKeyPrefix      = "landau:idem:"
+	idempotencyKeyMaxLength   = 255
+)
+
+var (
+	idempotentURL          = make(map[string]bool) //key: url path(RESTFul 为注册的 url 模板)
+	idempotentAction       = make(map[string]bool) //key: action
+	syncIdempotent         = sync.RWMutex{}
+	idempotencyStore       = data.NewMemoryIdempotencyStore()
+	idempotencyTTL         = 24 * time.Hour
+	idempotencyLockTTL     = time.Minute
+	idempotencyCaller      IdempotencyCaller
+	idempotencyConflictRsp interface{} = gin.H{
+		"Code":    409,
+		"Message": "A request with the same Idempotency-Key is being processed",
+	}
+	idempotencyMismatchRsp interface{} = gin.H{
+		"Code":    422,
+		"Message": "Idempotency-Key has been used with a different request",
+	}
+)
+
+// AddIdempotentAction 对 Action 启用 Idempotency-Key 保护, 需要同时使用 SetIdempotencyCaller 设置调用方标识
+func AddIdempotentAction(actions ...string) {
+	syncIdempotent.Lock()
+	defer syncIdempotent.Unlock()
+	for _, a := range actions {
+		idempotentAction[a] = true
+	}
+}
+
+// AddIdempotentURL 对 URL 启用 Idempotency-Key 保护, RESTFul 入口使用注册时的 url 模板(如 /user/:id), 需要同时使用 SetIdempotencyCaller 设置调用方标识
+func AddIdempotentURL(urlPaths ...string) {
+	syncIdempotent.Lock()
+	defer syncIdempotent.Unlock()
+	for _, u := range urlPaths {
+		idempotentURL[u] = true
+	}
+}
+
+// SetIdempotencyStore 设置幂等记录存储, 默认本地内存, 集群部署使用 data.NewRedisIdempotencyStore
+func SetIdempotencyStore(store data.IdempotencyStore) {
+	idempotencyStore = store
+}
+
+// SetIdempotencyTTL 设置应答重放有效期(默认 24 小时)及处理中标记有效期(默认 1 分钟, 应大于接口最长处理时间)
+func SetIdempotencyTTL(ttl time.Duration, lockTTL time.Duration) {
+	if ttl > 0 {
+		idempotencyTTL = ttl
+	}
+	if lockTTL > 0 {
+		idempotencyLockTTL = lockTTL
+	}
+}
+
+// SetIdempotencyCaller 设置调用方标识提取函数, 未设置时不做幂等保护;
+// 不要使用客户端 IP: 同一网关/NAT 后的调用方会重放彼此的应答
+func SetIdempotencyCaller(caller IdempotencyCaller) {
+	idempotencyCaller = caller
+}
+
+// SetIdempotencyConflictResponse 设置相同 Idempotency-Key 请求处理中时的应答内容, HTTP 状态码为 409
+func SetIdempotencyConflictResponse(response interface{}) {
+	idempotencyConflictRsp = response
+}
+
+// SetIdempotencyMismatchResponse 设置 Idempotency-Key 被不同请求内容重用时的应答内容, HTTP 状态码为 422
+func SetIdempotencyMismatchResponse(response interface{}) {
+	idempotencyMismatchRsp = response
+}
+
+func isIdempotent(urlPath, action string) bool {
+	syncIdempotent.RLock()
+	defer syncIdempotent.RUnlock()
+	if action != "" {
+		return idempotentAction[action]
+	}
+	return idempotentURL[urlPath]
+}
+
+// beginIdempotency 受保护的 URL/Action 携带 Idempotency-Key 时: 首次请求标记处理中并返回 nil,
+// 已有应答时返回重放内容(请求内容不一致时返回 422 应答), 处理中返回冲突应答. 未设置调用方标识或存储访问失败时不做保护
+func beginIdempotency(c *gin.Context, urlPath, action string) interface{} {
+	idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+	if idempotencyKey == "" || len(idempotencyKey) > idempotencyKeyMaxLength || !isIdempotent(urlPath, action) {
+		return nil
+	}
+	scope := "url:" + urlPath
+	if action != "" {
+		scope = "action:" + action
+	}
+	if idempotencyCaller == nil {
+		log.Error2(defaultAPILogger, "[Idempotency] scope:%s caller not set, use api.SetIdempotencyCaller", scope)
+		return nil
+	}
+	caller := idempotencyCaller(c)
+	if caller == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(caller + "\n" + scope + "\n" + idempotencyKey))
+	key := idempotencyKeyPrefix + hex.EncodeToString(sum[:])
+	fingerprint := idempotencyFingerprint(c)
+	record, started, err := idempotencyStore.Begin(key, idempotencyLockTTL)
+	switch {
+	case err != nil:
+		log.Error2(defaultAPILogger, "[Idempotency] scope:%s key:%s error:%v", scope, idempotencyKey, err)
+		return nil
+	case started:
+		c.Set(idempotencyContextKey, &_IdempotencyCall{key: key, fingerprint: fingerprint})
+		return nil
+	case record != nil && record.Fingerprint != fingerprint:
+		setResponseHTTPCode(c, http.StatusUnprocessableEntity)
+		return idempotencyMismatchRsp
+	case record != nil:
+		c.Header(IdempotencyReplayedHeader, "true")
+		setResponseHTTPCode(c, record.Status)
+		return record.Body
+	default:
+		setResponseHTTPCode(c, http.StatusConflict)
+		return idempotencyConflictRsp
+	}
+}
+
+// idempotencyFingerprint 请求方法, 路径(含 query)及请求内容的 hash
+func idempotencyFingerprint(c *gin.Context) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.Method + "\n" + c.Request.URL.RequestURI() + "\n"))
+	if cb, ok := c.Get(requestRawParams); ok {
+		if body, ok := cb.([]byte); ok {
+			h.Write(body)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// completeIdempotency 保存首次处理的应答, 处理超时时不保存, 由 abortIdempotency 在处理结束后清除处理中标记
+func completeIdempotency(c *gin.Context, httpCode int, response interface{}) {
+	v, ok := c.Get(idempotencyContextKey)
+	if !ok || isHandleTimeout(c) {
+		return
+	}
+	call := v.(*_IdempotencyCall)
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.Error2(defaultAPILogger, "[Idempotency] marshal response error:%v", err)
+		return
+	}
+	call.completed = true
+	if err = idempotencyStore.Complete(call.key, &data.IdempotencyRecord{Status: httpCode, Body: body, Fingerprint: call.fingerprint}, idempotencyTTL); err != nil {
+		log.Error2(defaultAPILogger, "[Idempotency] save response error:%v", err)
+	}
+}
+
+// abortIdempotency 未保存应答(如 panic, 处理超时)时清除处理中标记, 处理超时时在超时的 handle 结束后清除
+func abortIdempotency(c *gin.Context) {
+	if v, ok := c.Get(idempotencyContextKey); ok {
+		if call := v.(*_IdempotencyCall); !call.completed {
+			releaseAfterHandle(c, func() { _ = idempotencyStore.Abort(call.key) })
+		}
+	}
+}
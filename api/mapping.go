@@ -70,19 +70,19 @@ func mapForm(ptr interface{}, form map[string][]string) error {
 			slice := reflect.MakeSlice(structField.Type(), numElems, numElems)
 			for i := 0; i < numElems; i++ {
 				if err := setWithProperType(sliceOf, inputValue[i], slice.Index(i)); err != nil {
-					return err
+					return &bindValueError{Field: inputFieldName, Value: inputValue[i], Err: err}
 				}
 			}
 			val.Field(i).Set(slice)
 		} else {
 			if _, isTime := structField.Interface().(time.Time); isTime {
 				if err := setTimeField(inputValue[0], typeField, structField); err != nil {
-					return err
+					return &bindValueError{Field: inputFieldName, Value: inputValue[0], Err: err}
 				}
 				continue
 			}
 			if err := setWithProperType(typeField.Type.Kind(), inputValue[0], structField); err != nil {
-				return err
+				return &bindValueError{Field: inputFieldName, Value: inputValue[0], Err: err}
 			}
 		}
 	}
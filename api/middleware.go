@@ -0,0 +1,95 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	//Middleware 请求处理中间件, 作用于 AddHTTPHandle/AddRESTFulAPIHttpHandle 注册的入口, 各阶段函数可以为空.
+	//BeforeBind/AfterBind 返回非 nil 时终止后续处理, 以返回值作为 response 应答(仍记录日志及指标);
+	//AfterHandle 返回值替换 response
+	Middleware struct {
+		Name        string                                                                    //名称
+		BeforeBind  func(c *gin.Context) interface{}                                          //绑定参数之前
+		AfterBind   func(c *gin.Context, param interface{}) interface{}                       //绑定参数之后, param 为请求参数(指针)
+		AfterHandle func(c *gin.Context, param interface{}, response interface{}) interface{} //处理之后
+	}
+	middlewareChain []Middleware
+)
+
+var (
+	globalMiddleware []Middleware
+	urlMiddleware    = make(map[string][]Middleware) //key: url path(RESTFul 为注册的 url 模板)
+	actionMiddleware = make(map[string][]Middleware) //key: action
+	syncMiddleware   = sync.RWMutex{}
+)
+
+// UseMiddleware 注册全局中间件, 先于 URL/Action 中间件执行
+func UseMiddleware(m ...Middleware) {
+	syncMiddleware.Lock()
+	defer syncMiddleware.Unlock()
+	globalMiddleware = append(globalMiddleware, m...)
+}
+
+// UseURLMiddleware 注册 URL 中间件, RESTFul 入口使用注册时的 url 模板(如 /user/:id)
+func UseURLMiddleware(urlPath string, m ...Middleware) {
+	syncMiddleware.Lock()
+	defer syncMiddleware.Unlock()
+	urlMiddleware[urlPath] = append(urlMiddleware[urlPath], m...)
+}
+
+// UseActionMiddleware 注册 Action 中间件(通过 / 按 Action 分发的请求), 在全局及 URL(/) 中间件之后执行
+func UseActionMiddleware(action string, m ...Middleware) {
+	syncMiddleware.Lock()
+	defer syncMiddleware.Unlock()
+	actionMiddleware[action] = append(actionMiddleware[action], m...)
+}
+
+// getMiddlewareChain 依次为 全局, URL, Action 中间件
+func getMiddlewareChain(urlPath string, action string) middlewareChain {
+	syncMiddleware.RLock()
+	defer syncMiddleware.RUnlock()
+	var chain middlewareChain
+	chain = append(chain, globalMiddleware...)
+	if urlPath != "" {
+		chain = append(chain, urlMiddleware[urlPath]...)
+	}
+	if action != "" {
+		chain = append(chain, actionMiddleware[action]...)
+	}
+	return chain
+}
+
+func (m middlewareChain) beforeBind(c *gin.Context) interface{} {
+	for _, w := range m {
+		if w.BeforeBind != nil {
+			if response := w.BeforeBind(c); response != nil {
+				return response
+			}
+		}
+	}
+	return nil
+}
+
+func (m middlewareChain) afterBind(c *gin.Context, param interface{}) interface{} {
+	for _, w := range m {
+		if w.AfterBind != nil {
+			if response := w.AfterBind(c, param); response != nil {
+				return response
+			}
+		}
+	}
+	return nil
+}
+
+// afterHandle 逆序执行, 先注册的中间件最后处理 response
+func (m middlewareChain) afterHandle(c *gin.Context, param interface{}, response interface{}) interface{} {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].AfterHandle != nil {
+			response = m[i].AfterHandle(c, param, response)
+		}
+	}
+	return response
+}
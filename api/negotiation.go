@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gin-gonic/gin/render"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	//ResponseFormatJSON json 应答
+	ResponseFormatJSON = "json"
+	//ResponseFormatXML xml 应答
+	ResponseFormatXML = "xml"
+	//ResponseFormatMsgPack MessagePack 应答
+	ResponseFormatMsgPack = "msgpack"
+	//ResponseFormatProtoBuf protobuf 应答, 仅 response 实现 proto.Message 时有效, 否则使用 json
+	ResponseFormatProtoBuf = "protobuf"
+)
+
+var (
+	responseFormatQueryName = "_format" //指定应答格式的 query 参数名, 优先于 Accept
+	responseFormatEnabled   = map[string]bool{ResponseFormatXML: false, ResponseFormatMsgPack: false, ResponseFormatProtoBuf: false}
+	responseFormatMIME      = map[string]string{
+		binding.MIMEJSON:       ResponseFormatJSON,
+		binding.MIMEXML:        ResponseFormatXML,
+		binding.MIMEXML2:       ResponseFormatXML,
+		binding.MIMEMSGPACK:    ResponseFormatMsgPack,
+		binding.MIMEMSGPACK2:   ResponseFormatMsgPack,
+		binding.MIMEPROTOBUF:   ResponseFormatProtoBuf,
+		"application/protobuf": ResponseFormatProtoBuf,
+	}
+)
+
+// SetResponseFormatQuery 设置指定应答格式的 query 参数名(默认 _format, 如 ?_format=msgpack), 为空时仅根据 Accept 选择
+func SetResponseFormatQuery(queryName string) {
+	responseFormatQueryName = queryName
+}
+
+// SetResponseFormatEnable 启用/禁用 xml, msgpack, protobuf 应答格式, 默认全部禁用(仅 json), 需要时逐个启用
+func SetResponseFormatEnable(format string, enable bool) {
+	responseFormatEnabled[format] = enable
+}
+
+// getResponseFormat 根据 query 参数或 Accept(按 q 值)选择应答格式, 浏览器请求(Accept 含 text/html)不根据 Accept 选择
+func getResponseFormat(c *gin.Context) string {
+	if responseFormatQueryName != "" {
+		if f := strings.ToLower(c.Query(responseFormatQueryName)); f != "" {
+			if responseFormatEnabled[f] {
+				return f
+			}
+			return ResponseFormatJSON
+		}
+	}
+	accept := c.GetHeader("Accept")
+	if accept == "" || strings.Contains(accept, binding.MIMEHTML) {
+		return ResponseFormatJSON
+	}
+	type acceptItem struct {
+		format string
+		q      float64
+	}
+	var items []acceptItem
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if f, ok := responseFormatMIME[mediaType]; ok && q > 0 && (f == ResponseFormatJSON || responseFormatEnabled[f]) {
+			items = append(items, acceptItem{format: f, q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	if len(items) > 0 {
+		return items[0].format
+	}
+	return ResponseFormatJSON
+}
+
+// renderNegotiated 按协商的格式输出 xml/msgpack/protobuf, 返回 false 时由调用方按 json 输出;
+// json.RawMessage(如幂等重放的应答)无法转换为其他格式, 无法编码为 xml 的应答(如含 map 字段的结构体), 按 json 输出
+func renderNegotiated(c *gin.Context, httpCode int, response interface{}) bool {
+	if _, ok := response.(json.RawMessage); ok {
+		return false
+	}
+	switch getResponseFormat(c) {
+	case ResponseFormatXML:
+		b, err := xmlMarshal(response) //先编码, 失败时应答头尚未写入
+		if err != nil {
+			return false
+		}
+		c.Data(httpCode, binding.MIMEXML+"; charset=utf-8", b)
+	case ResponseFormatMsgPack:
+		c.Render(httpCode, render.MsgPack{Data: response})
+	case ResponseFormatProtoBuf:
+		if _, ok := response.(proto.Message); !ok {
+			return false
+		}
+		c.Render(httpCode, render.ProtoBuf{Data: response})
+	default:
+		return false
+	}
+	return true
+}
+
+// _XMLMap 以 key 为元素名编码 map, 嵌套的 map 保留其 key(gin.H 嵌套时元素名为 map)
+type _XMLMap map[string]interface{}
+
+// MarshalXML 按 key 排序输出
+func (m _XMLMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(m[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// xmlMarshal map(含 gin.H 及嵌套在 map, []interface{} 中的)以 <map> 为根元素编码, 其他类型使用 xml.Marshal
+func xmlMarshal(response interface{}) ([]byte, error) {
+	v := xmlValue(response)
+	if _, ok := v.(_XMLMap); !ok {
+		return xml.Marshal(v)
+	}
+	var b bytes.Buffer
+	if err := xml.NewEncoder(&b).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "map"}}); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func xmlValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(_XMLMap, len(t))
+		for k, item := range t {
+			m[k] = xmlValue(item)
+		}
+		return m
+	case gin.H:
+		return xmlValue(map[string]interface{}(t))
+	case []interface{}:
+		items := make([]interface{}, len(t))
+		for i, item := range t {
+			items[i] = xmlValue(item)
+		}
+		return items
+	}
+	return v
+}
+
+// bindPostNegotiated 按 Content-Type 绑定 xml/msgpack/protobuf 请求, 返回 false 时由调用方按 json/form 绑定;
+// protobuf 请求的参数未实现 proto.Message 时(如 Action)从 query 绑定
+func bindPostNegotiated(obj interface{}, c *gin.Context, body []byte) (bool, error) {
+	if string(body) == "{}" { //prepareRequestParam 空 body 的替代值
+		body = nil
+	}
+	switch c.ContentType() {
+	case binding.MIMEXML, binding.MIMEXML2:
+		return true, binding.XML.BindBody(body, obj)
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		if len(body) == 0 {
+			return true, binding.Validator.ValidateStruct(obj)
+		}
+		return true, binding.MsgPack.BindBody(body, obj)
+	case binding.MIMEPROTOBUF, "application/protobuf":
+		if _, ok := obj.(proto.Message); !ok {
+			return true, bindQuery(obj, c)
+		}
+		if err := binding.ProtoBuf.BindBody(body, obj); err != nil {
+			return true, err
+		}
+		return true, binding.Validator.ValidateStruct(obj)
+	}
+	return false, nil
+}
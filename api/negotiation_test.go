@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRenderNegotiatedXML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetResponseFormatEnable(ResponseFormatXML, true)
+	defer SetResponseFormatEnable(ResponseFormatXML, false)
+	render := func(response interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/?_format=xml", nil)
+		if !renderNegotiated(c, http.StatusOK, response) {
+			c.JSON(http.StatusOK, response)
+		}
+		return w
+	}
+
+	w := render(gin.H{"Code": 0, "Data": map[string]interface{}{"Name": "landau", "Tags": []interface{}{"a", map[string]interface{}{"b": 1}}}})
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/xml") || w.Body.Len() == 0 {
+		t.Fatalf("expect xml body, got %q %q", w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if expect := `<map><Code>0</Code><Data><Name>landau</Name><Tags>a</Tags><Tags><b>1</b></Tags></Data></map>`; w.Body.String() != expect {
+		t.Fatalf("expect %s, got %s", expect, w.Body.String())
+	}
+
+	type withMap struct {
+		Code int
+		Data map[string]int
+	}
+	w = render(withMap{Data: map[string]int{"a": 1}})
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") || w.Body.String() != `{"Code":0,"Data":{"a":1}}` {
+		t.Fatalf("expect json fallback, got %q %q", w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
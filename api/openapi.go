@@ -0,0 +1,577 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// OpenAPIDocument OpenAPI 3 文档
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Servers    []OpenAPIServer                         `json:"servers,omitempty"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components OpenAPIComponents                       `json:"components"`
+	}
+	// OpenAPIInfo 文档描述信息
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+	// OpenAPIServer 服务地址
+	OpenAPIServer struct {
+		URL         string `json:"url"`
+		Description string `json:"description,omitempty"`
+	}
+	// OpenAPIComponents 公共定义
+	OpenAPIComponents struct {
+		Schemas map[string]*OpenAPISchema `json:"schemas,omitempty"`
+	}
+	// OpenAPIOperation 接口定义
+	OpenAPIOperation struct {
+		Tags        []string                    `json:"tags,omitempty"`
+		Summary     string                      `json:"summary,omitempty"`
+		Description string                      `json:"description,omitempty"`
+		OperationID string                      `json:"operationId,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+		Deprecated  bool                        `json:"deprecated,omitempty"`
+	}
+	// OpenAPIParameter 请求参数
+	OpenAPIParameter struct {
+		Name        string         `json:"name"`
+		In          string         `json:"in"`
+		Description string         `json:"description,omitempty"`
+		Required    bool           `json:"required,omitempty"`
+		Schema      *OpenAPISchema `json:"schema,omitempty"`
+	}
+	// OpenAPIRequestBody 请求内容
+	OpenAPIRequestBody struct {
+		Required bool                         `json:"required,omitempty"`
+		Content  map[string]*OpenAPIMediaType `json:"content"`
+	}
+	// OpenAPIResponse 响应内容
+	OpenAPIResponse struct {
+		Description string                       `json:"description"`
+		Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+	}
+	// OpenAPIMediaType 内容类型
+	OpenAPIMediaType struct {
+		Schema *OpenAPISchema `json:"schema,omitempty"`
+	}
+	// OpenAPIDiscriminator oneOf 区分字段
+	OpenAPIDiscriminator struct {
+		PropertyName string            `json:"propertyName"`
+		Mapping      map[string]string `json:"mapping,omitempty"`
+	}
+	// OpenAPISchema 数据结构定义
+	OpenAPISchema struct {
+		Ref                  string                    `json:"$ref,omitempty"`
+		Type                 string                    `json:"type,omitempty"`
+		Format               string                    `json:"format,omitempty"`
+		Description          string                    `json:"description,omitempty"`
+		Enum                 []interface{}             `json:"enum,omitempty"`
+		Default              interface{}               `json:"default,omitempty"`
+		Minimum              *float64                  `json:"minimum,omitempty"`
+		Maximum              *float64                  `json:"maximum,omitempty"`
+		MinLength            *uint64                   `json:"minLength,omitempty"`
+		MaxLength            *uint64                   `json:"maxLength,omitempty"`
+		MinItems             *uint64                   `json:"minItems,omitempty"`
+		MaxItems             *uint64                   `json:"maxItems,omitempty"`
+		Items                *OpenAPISchema            `json:"items,omitempty"`
+		Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+		AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+		Required             []string                  `json:"required,omitempty"`
+		AllOf                []*OpenAPISchema          `json:"allOf,omitempty"`
+		OneOf                []*OpenAPISchema          `json:"oneOf,omitempty"`
+		Discriminator        *OpenAPIDiscriminator     `json:"discriminator,omitempty"`
+		Deprecated           bool                      `json:"deprecated,omitempty"`
+	}
+	_OpenAPIBuilder struct {
+		doc   *OpenAPIDocument
+		names map[reflect.Type]string
+		types map[string]reflect.Type
+	}
+)
+
+const (
+	openAPIVersion       = "3.0.3"
+	openAPISchemaRefRoot = "#/components/schemas/"
+	openAPIJSONMime      = "application/json"
+	openAPIFormMime      = "application/x-www-form-urlencoded"
+)
+
+var (
+	openAPIInfo          = OpenAPIInfo{Version: "1.0.0"}
+	openAPIServers       []OpenAPIServer
+	openAPINameSanitizer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+	openAPIRestfulMethod = []string{"get", "post", "put", "patch", "delete"}
+	openAPITimeType      = reflect.TypeOf(time.Time{})
+)
+
+// SetOpenAPIInfo 设置 OpenAPI 文档标题,版本,描述, title 为空时使用 data.ServiceName
+func SetOpenAPIInfo(title, version, description string) {
+	openAPIInfo = OpenAPIInfo{Title: title, Version: version, Description: description}
+}
+
+// SetOpenAPIServers 设置 OpenAPI 文档服务地址
+func SetOpenAPIServers(servers ...OpenAPIServer) {
+	openAPIServers = servers
+}
+
+// RegisterOpenAPIHandle 向gin注册 OpenAPI 文档输出入口
+func RegisterOpenAPIHandle(r gin.IRouter, path string) {
+	r.GET(path, OpenAPIHandle)
+}
+
+// OpenAPIHandle 输出 OpenAPI 文档
+func OpenAPIHandle(c *gin.Context) {
+	c.JSON(http.StatusOK, GenerateOpenAPI())
+}
+
+// GenerateOpenAPI 根据已注册的 URL, Action, RESTful 入口生成 OpenAPI 3 文档, Action 入口以 Action 字段区分的 oneOf 描述在 / 下
+func GenerateOpenAPI() *OpenAPIDocument {
+	info := openAPIInfo
+	if info.Title == "" {
+		info.Title = data.ServiceName
+	}
+	if info.Title == "" {
+		info.Title = "landau"
+	}
+	b := &_OpenAPIBuilder{
+		doc: &OpenAPIDocument{
+			OpenAPI:    openAPIVersion,
+			Info:       info,
+			Servers:    openAPIServers,
+			Paths:      make(map[string]map[string]*OpenAPIOperation),
+			Components: OpenAPIComponents{Schemas: make(map[string]*OpenAPISchema)},
+		},
+		names: make(map[reflect.Type]string),
+		types: make(map[string]reflect.Type),
+	}
+	for urlPath, a := range httpEntry {
+		if urlPath == "/" {
+			continue
+		}
+		b.addURLEntry(urlPath, a)
+	}
+	if len(httpActionEntry) > 0 || len(httpActionVersionEntry) > 0 {
+		b.addActionEntry()
+	}
+	for _, a := range restFulHttpEntry {
+		b.addRestfulEntry(a)
+	}
+	return b.doc
+}
+
+func (b *_OpenAPIBuilder) addOperation(path, method string, op *OpenAPIOperation) {
+	if _, ok := b.doc.Paths[path]; !ok {
+		b.doc.Paths[path] = make(map[string]*OpenAPIOperation)
+	}
+	op.OperationID = openAPIOperationID(method, path)
+	b.doc.Paths[path][method] = op
+}
+
+func (b *_OpenAPIBuilder) addURLEntry(urlPath string, a httpHandleEntry) {
+	reqType := openAPIRequestType(a.newRequesterParameter)
+	b.addOperation(urlPath, "get", &OpenAPIOperation{
+		Tags:       []string{"URL"},
+		Parameters: b.queryParameters(reqType, nil),
+		Responses:  b.responses(a.responseType),
+	})
+	b.addOperation(urlPath, "post", &OpenAPIOperation{
+		Tags:        []string{"URL"},
+		RequestBody: b.requestBody(reqType),
+		Responses:   b.responses(a.responseType),
+	})
+}
+
+// addActionEntry Action 请求以 Action.<Action> 描述, 多版本 Action 以 Action.<Action>.<Version> 描述, discriminator 指向默认版本
+func (b *_OpenAPIBuilder) addActionEntry() {
+	actionSet := make(map[string]bool)
+	for k := range httpActionEntry {
+		actionSet[k] = true
+	}
+	for k := range httpActionVersionEntry {
+		actionSet[k] = true
+	}
+	var actions []string
+	for k := range actionSet {
+		actions = append(actions, k)
+	}
+	sort.Strings(actions)
+	var enum []interface{}
+	var requests, responses []*OpenAPISchema
+	mapping := make(map[string]string)
+	for _, action := range actions {
+		enum = append(enum, action)
+		defaultEntry, defaultVersion, _ := resolveActionEntry(action, "")
+		versions := getActionVersions(action)
+		if _, ok := httpActionEntry[action]; ok {
+			versions = append([]string{""}, versions...)
+		}
+		for _, version := range versions {
+			a := httpActionEntry[action]
+			properties := map[string]*OpenAPISchema{"Action": {Type: "string", Enum: []interface{}{action}}}
+			name := "Action." + openAPINameSanitizer.ReplaceAllString(action, "_")
+			if version != "" {
+				a = httpActionVersionEntry[action][version]
+				properties["Version"] = &OpenAPISchema{Type: "string", Enum: []interface{}{version}}
+				name += "." + openAPINameSanitizer.ReplaceAllString(version, "_")
+			}
+			actionSchema := &OpenAPISchema{Type: "object", Properties: properties, Required: []string{"Action"}}
+			if s := b.schema(openAPIRequestType(a.newRequesterParameter)); s != nil {
+				actionSchema = &OpenAPISchema{AllOf: []*OpenAPISchema{s, actionSchema}}
+			}
+			actionSchema.Deprecated = isActionDeprecated(action, version)
+			b.doc.Components.Schemas[name] = actionSchema
+			requests = append(requests, &OpenAPISchema{Ref: openAPISchemaRefRoot + name})
+			if version == defaultVersion || mapping[action] == "" {
+				mapping[action] = openAPISchemaRefRoot + name
+			}
+		}
+		if defaultEntry != nil && defaultEntry.responseType != nil {
+			responses = append(responses, b.schema(defaultEntry.responseType))
+		}
+	}
+	body := &OpenAPISchema{OneOf: requests, Discriminator: &OpenAPIDiscriminator{PropertyName: "Action", Mapping: mapping}}
+	rsp := &OpenAPISchema{Type: "object"}
+	if len(responses) > 0 && len(responses) == len(actions) {
+		rsp = &OpenAPISchema{OneOf: responses}
+	}
+	rspContent := map[string]*OpenAPIResponse{
+		"200": {Description: "OK", Content: map[string]*OpenAPIMediaType{openAPIJSONMime: {Schema: rsp}}},
+	}
+	b.addOperation("/", "get", &OpenAPIOperation{
+		Tags:        []string{"Action"},
+		Description: "Action 请求, 其余 query 参数参见 components 中对应的 Action.<Action> 定义",
+		Parameters:  []*OpenAPIParameter{{Name: "Action", In: "query", Required: true, Schema: &OpenAPISchema{Type: "string", Enum: enum}}},
+		Responses:   rspContent,
+	})
+	b.addOperation("/", "post", &OpenAPIOperation{
+		Tags: []string{"Action"},
+		RequestBody: &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]*OpenAPIMediaType{openAPIJSONMime: {Schema: body}, openAPIFormMime: {Schema: body}},
+		},
+		Responses: rspContent,
+	})
+}
+
+func (b *_OpenAPIBuilder) addRestfulEntry(a _RESTFulApiEntry) {
+	reqType := openAPIRequestType(a.NewRequestParameter)
+	path, pathParams := a.Url, []*OpenAPIParameter(nil)
+	for _, name := range a.Params {
+		path = strings.Replace(strings.Replace(path, ":"+name, "{"+name+"}", 1), "*"+name, "{"+name+"}", 1)
+		paramSchema := &OpenAPISchema{Type: "string"}
+		if f, ok := openAPIRestfulField(reqType, name); ok {
+			paramSchema = b.schema(f.Type)
+		} else if f, ok := openAPIRestfulField(reqType, "id"); ok && name == a.ID {
+			paramSchema = b.schema(f.Type)
+		}
+		pathParams = append(pathParams, &OpenAPIParameter{Name: name, In: "path", Required: true, Schema: paramSchema})
+	}
+	methods := openAPIRestfulMethod
+	if a.HttpMethod != "" {
+		methods = []string{strings.ToLower(a.HttpMethod)}
+	}
+	for _, method := range methods {
+		op := &OpenAPIOperation{Tags: []string{"RESTful"}, Responses: b.responses(a.ResponseType)}
+		if method == "get" {
+			op.Parameters = b.queryParameters(reqType, pathParams)
+		} else {
+			op.Parameters = pathParams
+			op.RequestBody = b.requestBody(reqType)
+		}
+		b.addOperation(path, method, op)
+	}
+}
+
+func (b *_OpenAPIBuilder) responses(t reflect.Type) map[string]*OpenAPIResponse {
+	s := &OpenAPISchema{Type: "object"}
+	if t != nil {
+		s = b.schema(t)
+	}
+	return map[string]*OpenAPIResponse{
+		"200": {Description: "OK", Content: map[string]*OpenAPIMediaType{openAPIJSONMime: {Schema: s}}},
+	}
+}
+
+func (b *_OpenAPIBuilder) requestBody(t reflect.Type) *OpenAPIRequestBody {
+	if t == nil {
+		return nil
+	}
+	form := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	for _, p := range b.queryParameters(t, nil) {
+		form.Properties[p.Name] = p.Schema
+		if p.Required {
+			form.Required = append(form.Required, p.Name)
+		}
+	}
+	return &OpenAPIRequestBody{
+		Content: map[string]*OpenAPIMediaType{
+			openAPIJSONMime: {Schema: b.schema(t)},
+			openAPIFormMime: {Schema: form},
+		},
+	}
+}
+
+// queryParameters 按 mapForm 规则(form tag, 无 tag 的 struct 字段展开)生成 query 参数
+func (b *_OpenAPIBuilder) queryParameters(t reflect.Type, params []*OpenAPIParameter) []*OpenAPIParameter {
+	t = openAPIIndirect(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return params
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("restful") != "" {
+			continue
+		}
+		formTag := strings.Split(f.Tag.Get("form"), ",")
+		name := formTag[0]
+		if name == "" {
+			name = f.Name
+			if ft := openAPIIndirect(f.Type); ft.Kind() == reflect.Struct && ft != openAPITimeType {
+				params = b.queryParameters(ft, params)
+				continue
+			}
+		}
+		s := b.schema(f.Type)
+		if len(formTag) > 1 && strings.HasPrefix(formTag[1], "default=") {
+			s = openAPIWithDefault(s, strings.TrimPrefix(formTag[1], "default="))
+		}
+		required := openAPIApplyBinding(s, f.Tag.Get("binding"))
+		params = append(params, &OpenAPIParameter{Name: name, In: "query", Required: required, Schema: s})
+	}
+	return params
+}
+
+// schema 生成类型定义, 命名 struct 放入 components 并返回引用
+func (b *_OpenAPIBuilder) schema(t reflect.Type) *OpenAPISchema {
+	t = openAPIIndirect(t)
+	if t == nil {
+		return nil
+	}
+	if t == openAPITimeType {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		additional := b.schema(t.Elem())
+		if additional == nil {
+			additional = &OpenAPISchema{}
+		}
+		return &OpenAPISchema{Type: "object", AdditionalProperties: additional}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.schemaName(t)
+			b.names[t] = name
+			b.doc.Components.Schemas[name] = &OpenAPISchema{Type: "object"}
+			b.doc.Components.Schemas[name] = b.structSchema(t)
+		}
+		return &OpenAPISchema{Ref: openAPISchemaRefRoot + name}
+	}
+	return &OpenAPISchema{}
+}
+
+func (b *_OpenAPIBuilder) structSchema(t reflect.Type) *OpenAPISchema {
+	s := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	b.structFields(t, s)
+	return s
+}
+
+// structFields 按 encoding/json 规则收集字段, 无 json tag 的匿名 struct 字段展开
+func (b *_OpenAPIBuilder) structFields(t reflect.Type, s *OpenAPISchema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonTag := strings.Split(f.Tag.Get("json"), ",")
+		name := jsonTag[0]
+		if name == "-" && len(jsonTag) == 1 {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if ft := openAPIIndirect(f.Type); ft.Kind() == reflect.Struct {
+				b.structFields(ft, s)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fs := b.schema(f.Type)
+		if fs == nil {
+			fs = &OpenAPISchema{}
+		}
+		if openAPIApplyBinding(fs, f.Tag.Get("binding")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+func (b *_OpenAPIBuilder) schemaName(t reflect.Type) string {
+	name := openAPINameSanitizer.ReplaceAllString(t.Name(), "_")
+	if exist, ok := b.types[name]; ok && exist != t {
+		name = openAPINameSanitizer.ReplaceAllString(t.PkgPath()+"."+t.Name(), "_")
+	}
+	b.types[name] = t
+	return name
+}
+
+// openAPIApplyBinding 将 binding tag 中 required,oneof,min,max,gte,lte 转换为约束, 返回是否必填
+func openAPIApplyBinding(s *OpenAPISchema, bindingTag string) bool {
+	required := false
+	if bindingTag == "" || s.Ref != "" {
+		return strings.Contains(","+bindingTag+",", ",required,")
+	}
+	for _, rule := range strings.Split(bindingTag, ",") {
+		kv := strings.SplitN(rule, "=", 2)
+		switch kv[0] {
+		case "required":
+			required = true
+		case "oneof":
+			if len(kv) == 2 {
+				for _, v := range strings.Fields(kv[1]) {
+					s.Enum = append(s.Enum, openAPIValue(s, v))
+				}
+			}
+		case "min", "gte":
+			if len(kv) == 2 {
+				openAPISetLimit(s, kv[1], true)
+			}
+		case "max", "lte":
+			if len(kv) == 2 {
+				openAPISetLimit(s, kv[1], false)
+			}
+		}
+	}
+	return required
+}
+
+func openAPISetLimit(s *OpenAPISchema, v string, isMin bool) {
+	switch s.Type {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			if isMin {
+				s.Minimum = &f
+			} else {
+				s.Maximum = &f
+			}
+		}
+	case "string", "array":
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			switch {
+			case s.Type == "string" && isMin:
+				s.MinLength = &n
+			case s.Type == "string":
+				s.MaxLength = &n
+			case isMin:
+				s.MinItems = &n
+			default:
+				s.MaxItems = &n
+			}
+		}
+	}
+}
+
+func openAPIWithDefault(s *OpenAPISchema, v string) *OpenAPISchema {
+	if s.Ref != "" {
+		return s
+	}
+	s.Default = openAPIValue(s, v)
+	return s
+}
+
+func openAPIValue(s *OpenAPISchema, v string) interface{} {
+	switch s.Type {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if bv, err := strconv.ParseBool(v); err == nil {
+			return bv
+		}
+	}
+	return v
+}
+
+func openAPIRestfulField(t reflect.Type, key string) (reflect.StructField, bool) {
+	t = openAPIIndirect(t)
+	if t == nil || t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("restful") == key {
+			return f, true
+		}
+		if f.Anonymous && f.Tag.Get("restful") == "" {
+			if ef, ok := openAPIRestfulField(f.Type, key); ok {
+				return ef, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func openAPIRequestType(newRequesterParameter HTTPRequestParameter) reflect.Type {
+	if newRequesterParameter == nil {
+		return nil
+	}
+	return reflect.TypeOf(newRequesterParameter())
+}
+
+func openAPIIndirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func openAPIOperationID(method, path string) string {
+	id := strings.Trim(openAPINameSanitizer.ReplaceAllString(path, "_"), "_")
+	if id == "" {
+		return method + "_action"
+	}
+	return method + "_" + id
+}
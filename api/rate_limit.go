@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/prometheus"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	//RateLimitKeyFunc 提取调用方标识, 返回空字符串时该规则不限制此请求
+	RateLimitKeyFunc func(c *gin.Context) string
+	//RateLimitRule 限流规则, Rate 与 Concurrency 至少设置一项
+	RateLimitRule struct {
+		Name        string                  //规则名称, 用于计数 key, 日志及 Prometheus 指标
+		Algorithm   data.RateLimitAlgorithm //限流算法, 默认令牌桶
+		Rate        int                     //Window 时间内允许的请求数, 0 不限制
+		Burst       int                     //令牌桶容量, 默认等于 Rate
+		Window      time.Duration           //时间窗口, 默认 1 秒
+		Concurrency int                     //最大并发请求数, 0 不限制
+		KeyFunc     RateLimitKeyFunc        //调用方标识, 为空时所有调用方共用配额, 可使用 RateLimitByClientIP/RateLimitByHeader
+		Limiter     data.RateLimiter        //计数后端, 为空时使用本地内存, 集群限流使用 data.NewRedisRateLimiter
+	}
+	_RateLimitScope struct {
+		rule  *RateLimitRule
+		scope string
+	}
+)
+
+const (
+	rateLimitKeyPrefix     = "landau:rl:"
+	rateLimitConcurrentTTL = 10 * time.Minute
+)
+
+var (
+	globalRateLimit        []*RateLimitRule
+	urlRateLimit           = make(map[string][]*RateLimitRule) //key: url path(RESTFul 为注册的 url 模板)
+	actionRateLimit        = make(map[string][]*RateLimitRule) //key: action
+	syncRateLimit          = sync.RWMutex{}
+	defaultRateLimiter     = data.NewMemoryRateLimiter()
+	defaultRateLimitRsp    interface{}
+	serviceTooManyRequests = gin.H{
+		"Code":    429,
+		"Message": "Too Many Requests",
+	}
+)
+
+// AddRateLimit 注册全局限流规则, 所有 URL/Action 共用配额
+func AddRateLimit(rules ...RateLimitRule) {
+	syncRateLimit.Lock()
+	defer syncRateLimit.Unlock()
+	globalRateLimit = append(globalRateLimit, normalizeRateLimitRules(rules)...)
+}
+
+// AddURLRateLimit 注册 URL 限流规则, RESTFul 入口使用注册时的 url 模板(如 /user/:id)
+func AddURLRateLimit(urlPath string, rules ...RateLimitRule) {
+	syncRateLimit.Lock()
+	defer syncRateLimit.Unlock()
+	urlRateLimit[urlPath] = append(urlRateLimit[urlPath], normalizeRateLimitRules(rules)...)
+}
+
+// AddActionRateLimit 注册 Action 限流规则
+func AddActionRateLimit(action string, rules ...RateLimitRule) {
+	syncRateLimit.Lock()
+	defer syncRateLimit.Unlock()
+	actionRateLimit[action] = append(actionRateLimit[action], normalizeRateLimitRules(rules)...)
+}
+
+// SetRateLimitResponse 设置限流拒绝时的应答内容, HTTP 状态码为 429, 缺省 {"Code":429,"Message":"Too Many Requests"}
+func SetRateLimitResponse(response interface{}) {
+	defaultRateLimitRsp = response
+}
+
+// RateLimitByClientIP 以客户端 IP 区分调用方
+func RateLimitByClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// RateLimitByHeader 以请求头(如 X-Api-Key)区分调用方
+func RateLimitByHeader(header string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return c.GetHeader(header)
+	}
+}
+
+func normalizeRateLimitRules(rules []RateLimitRule) []*RateLimitRule {
+	var r []*RateLimitRule
+	for i := range rules {
+		rule := rules[i]
+		if rule.Window <= 0 {
+			rule.Window = time.Second
+		}
+		if rule.Burst <= 0 {
+			rule.Burst = rule.Rate
+		}
+		if rule.Limiter == nil {
+			rule.Limiter = defaultRateLimiter
+		}
+		r = append(r, &rule)
+	}
+	return r
+}
+
+func getRateLimitRules(urlPath, action string) []_RateLimitScope {
+	syncRateLimit.RLock()
+	defer syncRateLimit.RUnlock()
+	var rules []_RateLimitScope
+	for _, r := range globalRateLimit {
+		rules = append(rules, _RateLimitScope{rule: r})
+	}
+	if urlPath != "" {
+		for _, r := range urlRateLimit[urlPath] {
+			rules = append(rules, _RateLimitScope{rule: r, scope: "url:" + urlPath})
+		}
+	}
+	if action != "" {
+		for _, r := range actionRateLimit[action] {
+			rules = append(rules, _RateLimitScope{rule: r, scope: "action:" + action})
+		}
+	}
+	return rules
+}
+
+// acquireRateLimit 依次检查全局, URL, Action 限流规则; 返回释放并发配额的函数(总是非空),
+// 被拒绝时返回用于请求日志的拒绝描述. 后端访问失败时放行
+func acquireRateLimit(c *gin.Context, urlPath, action string) (func(), string) {
+	var acquired []func()
+	release := func() {
+		for _, f := range acquired {
+			f()
+		}
+	}
+	for _, s := range getRateLimitRules(urlPath, action) {
+		rule, callerKey := s.rule, ""
+		if rule.KeyFunc != nil {
+			if callerKey = rule.KeyFunc(c); callerKey == "" {
+				continue
+			}
+		}
+		key := fmt.Sprintf("%s%s:%s:%s", rateLimitKeyPrefix, rule.Name, s.scope, callerKey)
+		if rule.Rate > 0 {
+			allowed, err := rule.Limiter.Allow(key, rule.Algorithm, rule.Rate, rule.Burst, rule.Window)
+			if err != nil {
+				log.Error2(defaultAPILogger, "[RateLimit] rule:%s key:%s error:%v", rule.Name, key, err)
+			} else if !allowed {
+				release()
+				return func() {}, rateLimitRejected(c, rule, action, urlPath, callerKey, "rate")
+			}
+		}
+		if rule.Concurrency > 0 {
+			token, allowed, err := rule.Limiter.Acquire(key+":c", rule.Concurrency, rateLimitConcurrentTTL)
+			if err != nil {
+				log.Error2(defaultAPILogger, "[RateLimit] rule:%s key:%s error:%v", rule.Name, key, err)
+			} else if !allowed {
+				release()
+				return func() {}, rateLimitRejected(c, rule, action, urlPath, callerKey, "concurrency")
+			} else {
+				limiter, concurrentKey := rule.Limiter, key+":c"
+				acquired = append(acquired, func() { _ = limiter.Release(concurrentKey, token) })
+			}
+		}
+	}
+	return release, ""
+}
+
+func rateLimitRejected(c *gin.Context, rule *RateLimitRule, action, urlPath, callerKey, limitType string) string {
+	setResponseHTTPCode(c, http.StatusTooManyRequests)
+	prometheus.UpdateRateLimitMetric(rule.Name, action, urlPath)
+	return fmt.Sprintf("RateLimited rule:%s type:%s caller:%s", rule.Name, limitType, callerKey)
+}
+
+// rateLimitResponse 被限流时返回应答内容, 否则返回 nil
+func rateLimitResponse(rejected string) interface{} {
+	if rejected == "" {
+		return nil
+	}
+	if defaultRateLimitRsp != nil {
+		return defaultRateLimitRsp
+	}
+	return serviceTooManyRequests
+}
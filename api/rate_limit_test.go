@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitResponseCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const urlPath = "/test/rate_limit_code"
+	AddURLRateLimit(urlPath, RateLimitRule{Name: "test", Rate: 1, Window: time.Minute})
+	acquire := func() string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, urlPath, nil)
+		release, rejected := acquireRateLimit(c, urlPath, "")
+		release()
+		return rejected
+	}
+	if rejected := acquire(); rejected != "" {
+		t.Fatalf("first request rejected: %s", rejected)
+	}
+	rejected := acquire()
+	if rejected == "" {
+		t.Fatal("second request should be rate limited")
+	}
+	if code := getCodeFromInterface(rateLimitResponse(rejected)); code != http.StatusTooManyRequests {
+		t.Fatalf("expect recorded code 429, got %d", code)
+	}
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"github.com/NeilXu2017/landau/data"
+	"github.com/gin-gonic/gin"
+)
+
+// prepareRequestID 使用请求头 X-Request-Id(无效时重新生成), 写入响应头, gin.Context 及 Request.Context()
+func prepareRequestID(c *gin.Context) string {
+	requestID := c.GetHeader(data.RequestIDHeader)
+	if !data.IsValidRequestID(requestID) {
+		requestID = data.NewRequestID()
+	}
+	c.Header(data.RequestIDHeader, requestID)
+	c.Set(data.RequestIDContextKey, requestID)
+	c.Request = c.Request.WithContext(data.ContextWithRequestID(c.Request.Context(), requestID))
+	return requestID
+}
+
+// GetRequestID 返回当前请求的 request id, 也可以使用 data.RequestIDFromContext(c)
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(data.RequestIDContextKey)
+}
@@ -0,0 +1,279 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/prometheus"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	//ResponseCachePolicy 应答缓存策略, 缓存 key 由绑定后的请求参数, Action 版本及 Headers 组成, 仅缓存 Code 字段为 0 的 2xx 应答;
+	//命中时跳过处理程序, 中间件 AfterHandle 收到的 response 为 json.RawMessage
+	ResponseCachePolicy struct {
+		TTL                  time.Duration           //缓存有效期
+		StaleWhileRevalidate time.Duration           //过期后仍可使用的时间, 期间返回旧应答并在后台执行处理程序刷新缓存, 0 不使用
+		Headers              []string                //参与缓存 key 的请求头, 如 Authorization, Accept-Language
+		Store                data.ResponseCacheStore //缓存存储, 为空时使用 SetResponseCacheStore 设置的存储(默认本地内存 LRU)
+	}
+	//_DetachedContext 保留请求 context 的值(如 request id), 不继承其取消及 deadline, 用于请求结束后的后台刷新
+	_DetachedContext struct {
+		context.Context
+	}
+)
+
+const (
+	responseCacheKeyPrefix       = "landau:rc:"
+	responseCacheHit             = "hit"
+	responseCacheStale           = "stale"
+	responseCacheMiss            = "miss"
+	responseCacheStateContextKey = "landau_response_cache"
+)
+
+var (
+	urlResponseCache        = make(map[string]*ResponseCachePolicy) //key: url path(RESTFul 为注册的 url 模板)
+	actionResponseCache     = make(map[string]*ResponseCachePolicy) //key: action
+	syncResponseCache       = sync.RWMutex{}
+	responseCacheStore      = data.NewMemoryResponseCacheStore(0)
+	responseCacheRefreshing = sync.Map{} //后台刷新中的 key
+)
+
+// SetURLResponseCache 设置 URL 应答缓存策略, policy 为 nil 时取消; RESTFul 入口使用注册时的 url 模板(如 /user/:id), 仅缓存 GET 请求
+func SetURLResponseCache(urlPath string, policy *ResponseCachePolicy) {
+	syncResponseCache.Lock()
+	defer syncResponseCache.Unlock()
+	if policy == nil || policy.TTL <= 0 {
+		delete(urlResponseCache, urlPath)
+		return
+	}
+	urlResponseCache[urlPath] = policy
+}
+
+// SetActionResponseCache 设置 Action 应答缓存策略, policy 为 nil 时取消
+func SetActionResponseCache(action string, policy *ResponseCachePolicy) {
+	syncResponseCache.Lock()
+	defer syncResponseCache.Unlock()
+	if policy == nil || policy.TTL <= 0 {
+		delete(actionResponseCache, action)
+		return
+	}
+	actionResponseCache[action] = policy
+}
+
+// SetResponseCacheStore 设置应答缓存存储, 默认本地内存 LRU(10000 条), 集群部署使用 data.NewRedisResponseCacheStore
+func SetResponseCacheStore(store data.ResponseCacheStore) {
+	if store != nil {
+		responseCacheStore = store
+	}
+}
+
+// InvalidateURLResponseCache 删除 URL 的缓存应答; requests 为空时删除全部,
+// 否则仅删除与 requests(绑定后的请求参数结构体或其指针, 所有字段一致)对应的应答
+func InvalidateURLResponseCache(urlPath string, requests ...interface{}) error {
+	return invalidateResponseCache(urlPath, "", requests)
+}
+
+// InvalidateActionResponseCache 删除 Action(所有版本)的缓存应答, requests 参见 InvalidateURLResponseCache
+func InvalidateActionResponseCache(action string, requests ...interface{}) error {
+	return invalidateResponseCache("", action, requests)
+}
+
+func invalidateResponseCache(urlPath, action string, requests []interface{}) error {
+	policy := getResponseCachePolicy(urlPath, action)
+	if policy == nil {
+		return nil
+	}
+	store := policy.getStore()
+	scope := responseCacheScope(urlPath, action)
+	if len(requests) == 0 {
+		return store.DeletePrefix(scope)
+	}
+	for _, r := range requests {
+		_, paramHash, err := responseCacheParamHash(r)
+		if err != nil {
+			return err
+		}
+		if err = store.DeletePrefix(scope + paramHash + ":"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getResponseCachePolicy(urlPath, action string) *ResponseCachePolicy {
+	syncResponseCache.RLock()
+	defer syncResponseCache.RUnlock()
+	if action != "" {
+		return actionResponseCache[action]
+	}
+	return urlResponseCache[urlPath]
+}
+
+func (p *ResponseCachePolicy) getStore() data.ResponseCacheStore {
+	if p.Store != nil {
+		return p.Store
+	}
+	return responseCacheStore
+}
+
+// responseCacheScope URL/Action 的缓存 key 前缀, # 不会出现在请求路径中, 避免 url 模板互为前缀
+func responseCacheScope(urlPath, action string) string {
+	if action != "" {
+		return responseCacheKeyPrefix + "action:" + action + "#"
+	}
+	return responseCacheKeyPrefix + "url:" + urlPath + "#"
+}
+
+func responseCacheParamHash(param interface{}) (string, string, error) {
+	b, err := json.Marshal(param)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(b)
+	return string(b), hex.EncodeToString(sum[:16]), nil
+}
+
+// responseCacheKey 格式为 <scope><请求参数 hash>:<Action 版本>:<请求头 hash>, 按请求参数删除时可以覆盖所有版本及请求头
+func responseCacheKey(c *gin.Context, policy *ResponseCachePolicy, urlPath, action string, param interface{}) (string, string, error) {
+	strParam, paramHash, err := responseCacheParamHash(param)
+	if err != nil {
+		return "", "", err
+	}
+	headerHash := "-"
+	if len(policy.Headers) > 0 {
+		h := sha256.New()
+		for _, name := range policy.Headers {
+			h.Write([]byte(name + ":" + c.GetHeader(name) + "\n"))
+		}
+		headerHash = hex.EncodeToString(h.Sum(nil)[:16])
+	}
+	return responseCacheScope(urlPath, action) + paramHash + ":" + GetActionVersion(c) + ":" + headerHash, strParam, nil
+}
+
+// callHandleWithCache 设置了应答缓存时优先返回缓存的应答(跳过 handle), 未命中时执行 handle 并缓存应答;
+// 过期但在 StaleWhileRevalidate 时间内时返回旧应答, 同时以 c.Copy() 在后台执行 handle 刷新缓存
+func callHandleWithCache(c *gin.Context, urlPath, action string, httpCodeStatus string, param interface{}, handle func(c *gin.Context) (interface{}, string)) (interface{}, string) {
+	policy := getResponseCachePolicy(urlPath, action)
+	if policy == nil || getResponseFormat(c) != ResponseFormatJSON {
+		return handle(c)
+	}
+	key, strParam, err := responseCacheKey(c, policy, urlPath, action, param)
+	if err != nil {
+		log.Error2(defaultAPILogger, "[%s]\t[ResponseCache] action:%s build key error:%v\tRequestId:%s", c.Request.URL.Path, action, err, GetRequestID(c))
+		return handle(c)
+	}
+	store := policy.getStore()
+	entry, err := store.Get(key)
+	if err != nil {
+		log.Error2(defaultAPILogger, "[%s]\t[ResponseCache] action:%s get error:%v\tRequestId:%s", c.Request.URL.Path, action, err, GetRequestID(c))
+	}
+	state := responseCacheMiss
+	if entry != nil {
+		state = responseCacheHit
+		if time.Now().After(entry.Expire) {
+			state = responseCacheStale
+			refreshResponseCache(c, policy, key, action, httpCodeStatus, handle)
+		}
+	}
+	c.Set(responseCacheStateContextKey, state)
+	if entry == nil {
+		rsp, requestLog := handle(c)
+		saveResponseCache(c, policy, key, httpCodeStatus, rsp)
+		return rsp, requestLog
+	}
+	if entry.Status != http.StatusOK {
+		setResponseHTTPCode(c, entry.Status)
+	}
+	return entry.Body, fmt.Sprintf("ResponseCache:%s %s", state, strParam)
+}
+
+// getResponseCacheState 应答缓存结果 hit/stale/miss, 未使用缓存时为 none, 用于 API 指标的 cache 标签
+func getResponseCacheState(c *gin.Context) string {
+	if state := c.GetString(responseCacheStateContextKey); state != "" {
+		return state
+	}
+	return prometheus.CacheNone
+}
+
+// refreshResponseCache 同一 key 仅一个后台刷新, 使用不随请求结束取消的 context, 计入 in-flight 请求
+func refreshResponseCache(c *gin.Context, policy *ResponseCachePolicy, key, action, httpCodeStatus string, handle func(c *gin.Context) (interface{}, string)) {
+	if _, loaded := responseCacheRefreshing.LoadOrStore(key, true); loaded {
+		return
+	}
+	cp := c.Copy()
+	cp.Request = cp.Request.WithContext(_DetachedContext{Context: cp.Request.Context()})
+	cp.Writer = &_HandleBufferWriter{header: c.Writer.Header().Clone()} //请求已结束, 处理程序写入的应答头及内容丢弃
+	//计入 in-flight 请求, 优雅退出时等待刷新完成
+	data.InFlightAdd(data.InFlightHTTP)
+	go func() {
+		defer data.InFlightDone(data.InFlightHTTP)
+		defer responseCacheRefreshing.Delete(key)
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error2(defaultAPILogger, "[%s]\t[ResponseCache] action:%s refresh panic:%v\tRequestId:%s", cp.Request.URL.Path, action, p, GetRequestID(cp))
+			}
+		}()
+		rsp, _ := handle(cp)
+		saveResponseCache(cp, policy, key, httpCodeStatus, rsp)
+	}()
+}
+
+// saveResponseCache 缓存 Code 字段为 0 的 2xx 应答, 处理超时或被框架拦截(限流等)的应答不缓存
+func saveResponseCache(c *gin.Context, policy *ResponseCachePolicy, key, httpCodeStatus string, rsp interface{}) {
+	if rsp == nil || isHandleTimeout(c) {
+		return
+	}
+	httpCode := http.StatusOK
+	if httpCodeStatus != "" {
+		httpCode = getHttpStatusCodeFromResponseObject(rsp, httpCodeStatus, http.StatusOK)
+	}
+	httpCode = getResponseHTTPCode(c, httpCode)
+	if httpCode < 200 || httpCode >= 300 {
+		return
+	}
+	body, err := json.Marshal(rsp)
+	if err != nil {
+		log.Error2(defaultAPILogger, "[ResponseCache] marshal response error:%v", err)
+		return
+	}
+	if !isResponseCodeOK(body) {
+		return
+	}
+	entry := &data.ResponseCacheEntry{Status: httpCode, Body: body, Expire: time.Now().Add(policy.TTL)}
+	if err = policy.getStore().Set(key, entry, policy.TTL+policy.StaleWhileRevalidate); err != nil {
+		log.Error2(defaultAPILogger, "[ResponseCache] save response error:%v", err)
+	}
+}
+
+// isResponseCodeOK 应答(json 对象)的 Code 字段(不区分大小写)为 0; 不是对象, 没有 Code 或无法识别时返回 false
+func isResponseCodeOK(body []byte) bool {
+	var v struct {
+		Code *json.Number
+	}
+	if err := json.Unmarshal(body, &v); err != nil || v.Code == nil {
+		return false
+	}
+	f, err := v.Code.Float64()
+	return err == nil && f == 0
+}
+
+func (_DetachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (_DetachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (_DetachedContext) Err() error {
+	return nil
+}
@@ -7,10 +7,10 @@ import (
 	"io"
 	"net/http"
 	"reflect"
-	"regexp"
 	"strings"
 	"time"
 
+	"github.com/NeilXu2017/landau/data"
 	"github.com/NeilXu2017/landau/log"
 	"github.com/NeilXu2017/landau/prometheus"
 	"github.com/gin-gonic/gin"
@@ -23,16 +23,21 @@ type (
 		NewRequestParameter HTTPRequestParameter
 		HttpHandle          HTTPHandleFunc
 		_urls               []string
-		ID                  string
+		ID                  string   //最后一个 :name 段, restful:"id" 未匹配同名段时绑定此段
+		Params              []string //全部 :name 及 *name 段, 依次绑定到 restful:"name" 字段
 		HttpCodeStatus      string
-		UrlRegex            *regexp.Regexp
 		HttpMethod          string
 		InnerAPICode        string
+		ResponseType        reflect.Type
 	}
 )
 
 var (
-	restFulHttpEntry                = make(map[string]_RESTFulApiEntry)
+	restFulHttpEntry        = make(map[string]_RESTFulApiEntry) //key: HTTP 方法(为空时省略) + url 模板
+	serviceMethodNotAllowed = gin.H{
+		"Code":    405,
+		"Message": "Method Not Allowed",
+	}
 	replaceDefaultRestfulBindError  bool
 	defaultRestfulBindErrorResponse interface{}
 	defaultInnerAPICodeFieldName    = "InnerApiCode"
@@ -51,27 +56,46 @@ func AddRESTFulAPIHttpHandle3(urlPath string, newRequesterParameter HTTPRequestP
 }
 
 func AddRESTFulAPIHttpHandle4(urlPath string, newRequesterParameter HTTPRequestParameter, handleFunc HTTPHandleFunc, httpCodeFieldName string, httpMethod string, innerAPICodeFieldName string) {
+	AddRESTFulAPIHttpHandle5(urlPath, newRequesterParameter, handleFunc, httpCodeFieldName, httpMethod, innerAPICodeFieldName, nil)
+}
+
+// AddRESTFulAPIHttpHandle5 注册RESTful处理程序, responseType 为响应结构体(或其指针)样例, 用于生成 OpenAPI 文档;
+// urlPath 支持 :name 参数段及最后一段 *name 通配段, 与已注册的路由冲突时 panic
+func AddRESTFulAPIHttpHandle5(urlPath string, newRequesterParameter HTTPRequestParameter, handleFunc HTTPHandleFunc, httpCodeFieldName string, httpMethod string, innerAPICodeFieldName string, responseType interface{}) {
 	urls, id := strings.Split(urlPath, "/"), ""
-	var keyUrl []string
+	var params []string
 	for _, u := range urls {
-		if strings.Index(u, ":") == 0 {
+		switch {
+		case strings.Index(u, ":") == 0:
 			id = strings.Replace(u, ":", "", 1)
-			keyUrl = append(keyUrl, "[^/]*")
-		} else {
-			keyUrl = append(keyUrl, u)
+			params = append(params, id)
+		case strings.Index(u, "*") == 0:
+			params = append(params, u[1:])
 		}
 	}
-	restFulHttpEntry[urlPath] = _RESTFulApiEntry{
+	a := _RESTFulApiEntry{
 		Url:                 urlPath,
 		NewRequestParameter: newRequesterParameter,
 		HttpHandle:          handleFunc,
 		_urls:               urls,
 		ID:                  id,
+		Params:              params,
 		HttpCodeStatus:      httpCodeFieldName,
-		UrlRegex:            regexp.MustCompile(fmt.Sprintf("^%s$", strings.Join(keyUrl, "/"))),
 		HttpMethod:          strings.ToUpper(httpMethod),
 		InnerAPICode:        innerAPICodeFieldName,
+		ResponseType:        reflect.TypeOf(responseType),
+	}
+	if err := checkHTTPEntryConflict(urlPath, a.HttpMethod); err != nil {
+		panic(err.Error())
+	}
+	if err := restFulRouter.add(&a); err != nil {
+		panic(err.Error())
+	}
+	key := urlPath
+	if a.HttpMethod != "" {
+		key = a.HttpMethod + " " + urlPath
 	}
+	restFulHttpEntry[key] = a
 }
 
 func SetDefaultRestfulBindError(replaced bool, replaceResponse interface{}) {
@@ -79,38 +103,46 @@ func SetDefaultRestfulBindError(replaced bool, replaceResponse interface{}) {
 	defaultRestfulBindErrorResponse = replaceResponse
 }
 
-func isExistRESTFul(urlPath string, httpMethod string) (*_RESTFulApiEntry, bool) {
-	requestURL := strings.Split(urlPath, "/")
-	var matchedEntry []_RESTFulApiEntry
-	for _, a := range restFulHttpEntry {
-		if (a.HttpMethod == "" || a.HttpMethod == httpMethod) && a.UrlRegex.MatchString(urlPath) && len(requestURL) == len(a._urls) {
-			isMatched := true
-			for i, j := 0, len(requestURL); i < j; i++ {
-				if requestURL[i] != a._urls[i] && strings.Index(a._urls[i], ":") != 0 {
-					isMatched = false
-					break
-				}
-			}
-			if isMatched {
-				if a.ID == "" { //no id has more high priority
-					return &a, true
-				}
-				matchedEntry = append(matchedEntry, a)
-			}
+// getPathParams 按注册的 url 模板提取请求路径中全部 :name 段的值, *name 为剩余路径
+func (a *_RESTFulApiEntry) getPathParams(urlPath string) map[string]string {
+	params := make(map[string]string, len(a.Params))
+	segments := strings.Split(urlPath, "/")
+	for i, u := range segments {
+		if i >= len(a._urls) {
+			break
+		}
+		switch {
+		case strings.Index(a._urls[i], ":") == 0:
+			params[a._urls[i][1:]] = u
+		case strings.Index(a._urls[i], "*") == 0:
+			params[a._urls[i][1:]] = strings.Join(segments[i:], "/")
 		}
 	}
-	if len(matchedEntry) > 0 {
-		if len(matchedEntry) == 1 {
-			return &matchedEntry[0], true
+	return params
+}
+
+// ginParams 按 url 模板顺序转换为 gin.Params, 兼容处理程序使用 c.Param(name); *name 与 gin 一致以 / 开头
+func (a *_RESTFulApiEntry) ginParams(params map[string]string) gin.Params {
+	ginParams := make(gin.Params, 0, len(a.Params))
+	for _, u := range a._urls {
+		switch {
+		case strings.Index(u, ":") == 0:
+			ginParams = append(ginParams, gin.Param{Key: u[1:], Value: params[u[1:]]})
+		case strings.Index(u, "*") == 0:
+			ginParams = append(ginParams, gin.Param{Key: u[1:], Value: "/" + params[u[1:]]})
 		}
-		log.Error2("[isExistRESTFul] requestUrl:%s have more than one matched,please check api entry:%v", urlPath, matchedEntry)
 	}
-	return nil, false
+	return ginParams
+}
+
+// setRestFulKeys 将路径参数绑定到 restful:"name" 字段(含匿名嵌入结构体), restful:"method" 绑定请求方法;
+// 路径段为空或类型转换失败时返回 bindValueError
+func setRestFulKeys(ptr interface{}, params map[string]string, ID, method string) error {
+	return setRestFulFields(reflect.ValueOf(ptr).Elem(), params, ID, method)
 }
 
-func setRestFulKeys(ptr interface{}, ID, method string) {
-	typ := reflect.TypeOf(ptr).Elem()
-	val := reflect.ValueOf(ptr).Elem()
+func setRestFulFields(val reflect.Value, params map[string]string, ID, method string) error {
+	typ := val.Type()
 	for i := 0; i < typ.NumField(); i++ {
 		typeField := typ.Field(i)
 		structField := val.Field(i)
@@ -118,17 +150,65 @@ func setRestFulKeys(ptr interface{}, ID, method string) {
 			continue
 		}
 		inputFieldName := typeField.Tag.Get("restful")
-		switch inputFieldName {
-		case "id":
-			_ = setWithProperType(typeField.Type.Kind(), ID, structField)
-		case "method":
+		if inputFieldName == "" || inputFieldName == "-" {
+			if typeField.Anonymous && structField.Kind() == reflect.Struct {
+				if err := setRestFulFields(structField, params, ID, method); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if inputFieldName == "method" {
 			_ = setWithProperType(typeField.Type.Kind(), method, structField)
+			continue
+		}
+		inputValue, ok := params[inputFieldName]
+		if !ok && inputFieldName == "id" && ID != "" {
+			inputValue, ok = params[ID]
+		}
+		if !ok { //url 模板中没有此段(如多个 url 共用参数结构体), 由 binding 校验是否必填
+			continue
+		}
+		if inputValue == "" {
+			return &bindValueError{Field: inputFieldName, Rule: "required", Source: BindSourcePath, Err: fmt.Errorf("restful path segment :%s is empty", inputFieldName)}
+		}
+		if err := setWithProperType(typeField.Type.Kind(), inputValue, structField); err != nil {
+			return &bindValueError{Field: inputFieldName, Value: inputValue, Rule: "type", Source: BindSourcePath, Err: err}
 		}
 	}
+	return nil
 }
 
-func restFullHttpHandleProxy(c *gin.Context) {
+// restFullHttpHandleProxy 处理匹配 RESTFul 路由的请求, 路径不匹配任何路由时返回 false
+func restFullHttpHandleProxy(c *gin.Context) bool {
 	urlPath := c.Request.URL.Path
+	if isCORSPreflight(c) {
+		a, allow := restFulRouter.lookup(strings.ToUpper(c.GetHeader("Access-Control-Request-Method")), urlPath)
+		switch {
+		case a != nil:
+			corsPreflight(c, a.Url, "")
+		case len(allow) > 0:
+			corsPreflight(c, urlPath, "")
+		default:
+			return false
+		}
+		return true
+	}
+	a, allow := restFulRouter.lookup(c.Request.Method, urlPath)
+	if a == nil {
+		if len(allow) == 0 {
+			return false
+		}
+		restFulMethodNotAllowed(c, allow)
+		return true
+	}
+	pathParams := a.getPathParams(urlPath)
+	c.Params = a.ginParams(pathParams)
+	data.InFlightAdd(data.InFlightHTTP)
+	defer data.InFlightDone(data.InFlightHTTP)
+	defer waitHandleTimeout(c)
+	defer abortIdempotency(c)
+	requestID := prepareRequestID(c)
 	var bodyBytes []byte
 	if c.Request.Body != nil {
 		bodyBytes, _ = io.ReadAll(c.Request.Body)
@@ -136,68 +216,101 @@ func restFullHttpHandleProxy(c *gin.Context) {
 	}
 	isBindingComplex := isPostBindingComplex(urlPath, "")
 	isPostMethod := c.Request.Method != "GET"
-	if a, existed := isExistRESTFul(urlPath, c.Request.Method); existed {
-		start := time.Now()
-		prepareRequestParam(c, isPostMethod)
-		var response interface{}
-		requestParamLog := ""
-		bizParamStruct := a.NewRequestParameter()
-		param, bindError := bindParamsRestful(c, &bizParamStruct, isPostMethod, isBindingComplex, c.Param(a.ID), c.Request.Method)
+	start := time.Now()
+	prepareRequestParam(c, isPostMethod)
+	chain := getMiddlewareChain(a.Url, "")
+	requestParamLog := ""
+	bizParamStruct := a.NewRequestParameter()
+	release, rejected := acquireRateLimit(c, a.Url, "")
+	defer releaseAfterHandle(c, release)
+	requestParamLog = rejected
+	param, response := bizParamStruct, rateLimitResponse(rejected)
+	if response == nil {
+		response = chain.beforeBind(c)
+	}
+	if response == nil {
+		var bindError error
+		param, bindError = bindParamsRestful(c, &bizParamStruct, isPostMethod, isBindingComplex, pathParams, a.ID, c.Request.Method)
 		if bindError == nil {
 			if _isCheckServiceNotReady("", a.Url) {
 				c.JSON(http.StatusTooEarly, serviceTooEarly)
-				return
+				return true
+			}
+			if response = beginIdempotency(c, a.Url, ""); response == nil {
+				if response = chain.afterBind(c, param); response == nil {
+					handle := func(c *gin.Context) (interface{}, string) {
+						return callHandleWithTimeout(c, a.Url, "", func(c *gin.Context) (interface{}, string) { return a.HttpHandle(c, param) })
+					}
+					if c.Request.Method == http.MethodGet {
+						response, requestParamLog = callHandleWithCache(c, a.Url, "", a.HttpCodeStatus, param, handle)
+					} else {
+						response, requestParamLog = handle(c)
+					}
+					_doMonitorAPIResult(response)
+					if !isHandleTimeout(c) { //超时的 handle 仍在执行, 不执行 AfterHandle
+						response = chain.afterHandle(c, param, response)
+					}
+				}
 			}
-			response, requestParamLog = a.HttpHandle(c, param)
-			_doMonitorAPIResult(response)
 		} else {
 			if replaceDefaultRestfulBindError {
 				response = defaultRestfulBindErrorResponse
 			} else {
-				response = gin.H{"Code": 230, "Message": fmt.Sprintf("Bind params error [%v]", bindError)}
+				response = newBindErrorResponse(c, param, bindError)
 			}
 		}
-		jsonpCallback := ""
-		if responseJSONPEnable {
-			jsonpCallback = c.DefaultQuery(responseJSONPCallbackQueryName, "")
-		}
-		addAccessControlAllowHeader(c)
-		httpCode := http.StatusOK
-		if a.HttpCodeStatus != "" {
-			httpCode = getHttpStatusCodeFromResponseObject(response, a.HttpCodeStatus, http.StatusOK)
-		}
-		if jsonpCallback == "" {
-			c.JSON(httpCode, response)
-		} else {
-			c.Render(httpCode, render.JsonpJSON{Callback: jsonpCallback, Data: response})
-		}
-		strCustomLogTag := ""
-		if customAPILogTag && httpCustomLogTag != nil {
-			strCustomLogTag = httpCustomLogTag(c)
-		}
-		strResponse := ""
-		if defaultResponseLogAsJSON {
-			if v, ok := response.(fmt.Stringer); ok {
-				strResponse = v.String()
-			} else {
-				byteResp, _ := json.Marshal(response)
-				strResponse = string(byteResp)
-			}
+	}
+	jsonpCallback := ""
+	if responseJSONPEnable {
+		jsonpCallback = c.DefaultQuery(responseJSONPCallbackQueryName, "")
+	}
+	addAccessControlAllowHeader(c, a.Url, "")
+	httpCode := http.StatusOK
+	if a.HttpCodeStatus != "" {
+		httpCode = getHttpStatusCodeFromResponseObject(response, a.HttpCodeStatus, http.StatusOK)
+	}
+	httpCode = getResponseHTTPCode(c, httpCode)
+	completeIdempotency(c, httpCode, response)
+	if jsonpCallback != "" {
+		c.Render(httpCode, render.JsonpJSON{Callback: jsonpCallback, Data: response})
+	} else if !renderNegotiated(c, httpCode, response) {
+		c.JSON(httpCode, response)
+	}
+	strCustomLogTag := ""
+	if customAPILogTag && httpCustomLogTag != nil {
+		strCustomLogTag = httpCustomLogTag(c)
+	}
+	strResponse := ""
+	if defaultResponseLogAsJSON {
+		if v, ok := response.(fmt.Stringer); ok {
+			strResponse = v.String()
 		} else {
-			strResponse = fmt.Sprintf("%v", response)
+			byteResp, _ := json.Marshal(response)
+			strResponse = string(byteResp)
 		}
-		log.Info2(defaultAPILogger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v", urlPath, time.Since(start), strCustomLogTag, requestParamLog, defaultLogResponse(strResponse))
-		pAction := getActionFromInterface(param)
-		extraLabelValues := prometheus.GetExtraLabelValue(pAction, a.Url, c.Request, response, c)
-		prometheus.UpdateApiMetric(getCodeFromInterface2(response, a.InnerAPICode), pAction, start, c.Request, a.Url, extraLabelValues)
+	} else {
+		strResponse = fmt.Sprintf("%v", response)
 	}
+	log.Info2(defaultAPILogger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v\tRequestId:%s", urlPath, time.Since(start), strCustomLogTag, requestParamLog, defaultLogResponse(strResponse), requestID)
+	pAction := getActionFromInterface(param)
+	extraLabelValues := prometheus.GetExtraLabelValue(pAction, a.Url, c.Request, response, c)
+	prometheus.UpdateApiCacheMetric(getCodeFromInterface2(response, a.InnerAPICode), pAction, start, c.Request, a.Url, getResponseCacheState(c), extraLabelValues)
+	return true
 }
 
-// RegisterRestfulHTTPHandle 向gin.Engine注册URL处理入口
+// restFulMethodNotAllowed 路径匹配但方法不允许时返回 405 及 Allow 头
+func restFulMethodNotAllowed(c *gin.Context, allow []string) {
+	start := time.Now()
+	requestID := prepareRequestID(c)
+	addAccessControlAllowHeader(c, c.Request.URL.Path, "")
+	c.Header("Allow", strings.Join(allow, ", "))
+	c.JSON(http.StatusMethodNotAllowed, serviceMethodNotAllowed)
+	log.Info2(defaultAPILogger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v\tRequestId:%s", c.Request.URL.Path, time.Since(start), c.Request.Method, "{}", serviceMethodNotAllowed, requestID)
+}
+
+// RegisterRestfulHTTPHandle 向gin.Engine注册URL处理入口, RESTFul 路由由 NoRoute 入口按路由树匹配
 func RegisterRestfulHTTPHandle(r *gin.Engine) {
-	for k := range restFulHttpEntry {
-		r.Any(k, restFullHttpHandleProxy)
-	}
+	r.NoRoute(noRouteHandle)
 }
 
 func getHttpStatusCodeFromResponseObject(ptr interface{}, fieldName string, defaultHttpCode int) int {
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type (
+	//_RESTFulRouteNode 按路径段构建的路由树节点, 匹配优先级: 静态段 > :name 参数段 > *name 通配段
+	_RESTFulRouteNode struct {
+		static   map[string]*_RESTFulRouteNode
+		param    *_RESTFulRouteNode
+		catchAll *_RESTFulRouteNode
+		entries  map[string]*_RESTFulApiEntry //key: HTTP 方法, 空字符串表示全部方法
+	}
+)
+
+var (
+	restFulRouter = newRESTFulRouteNode()
+)
+
+func newRESTFulRouteNode() *_RESTFulRouteNode {
+	return &_RESTFulRouteNode{static: make(map[string]*_RESTFulRouteNode)}
+}
+
+func splitRESTFulPath(urlPath string) []string {
+	return strings.Split(strings.TrimPrefix(urlPath, "/"), "/")
+}
+
+// add 注册路由, 路径结构(参数名不同视为相同)及方法与已注册路由重叠, 或 *name 不是最后一段时返回 error
+func (n *_RESTFulRouteNode) add(a *_RESTFulApiEntry) error {
+	segments := splitRESTFulPath(a.Url)
+	node := n
+	for i, s := range segments {
+		switch {
+		case strings.HasPrefix(s, "*"):
+			if i != len(segments)-1 {
+				return fmt.Errorf("[RESTFul] url:%s catch-all segment %s must be the last segment", a.Url, s)
+			}
+			if node.catchAll == nil {
+				node.catchAll = newRESTFulRouteNode()
+			}
+			node = node.catchAll
+		case strings.HasPrefix(s, ":"):
+			if node.param == nil {
+				node.param = newRESTFulRouteNode()
+			}
+			node = node.param
+		default:
+			child, ok := node.static[s]
+			if !ok {
+				child = newRESTFulRouteNode()
+				node.static[s] = child
+			}
+			node = child
+		}
+	}
+	if node.entries == nil {
+		node.entries = make(map[string]*_RESTFulApiEntry)
+	}
+	for method, e := range node.entries {
+		if method == "" || a.HttpMethod == "" || method == a.HttpMethod {
+			return fmt.Errorf("[RESTFul] url:%s method:%s conflicts with registered url:%s method:%s", a.Url, restFulMethodName(a.HttpMethod), e.Url, restFulMethodName(method))
+		}
+	}
+	node.entries[a.HttpMethod] = a
+	return nil
+}
+
+// lookup 返回匹配 urlPath 及 method 的路由; 路径匹配但方法不允许时返回 nil 及允许的方法, 路径不匹配时两者皆为空
+func (n *_RESTFulRouteNode) lookup(method, urlPath string) (*_RESTFulApiEntry, []string) {
+	var pathMatched *_RESTFulRouteNode
+	if a := n.find(splitRESTFulPath(urlPath), method, &pathMatched); a != nil {
+		return a, nil
+	}
+	if pathMatched == nil {
+		return nil, nil
+	}
+	var allow []string
+	for m := range pathMatched.entries {
+		allow = append(allow, m)
+	}
+	sort.Strings(allow)
+	return nil, allow
+}
+
+// find 按优先级深度优先匹配, 高优先级分支路径匹配但方法不允许时继续尝试低优先级分支, pathMatched 记录第一个路径匹配的节点
+func (n *_RESTFulRouteNode) find(segments []string, method string, pathMatched **_RESTFulRouteNode) *_RESTFulApiEntry {
+	if len(segments) == 0 {
+		return n.entry(method, pathMatched)
+	}
+	if child, ok := n.static[segments[0]]; ok {
+		if a := child.find(segments[1:], method, pathMatched); a != nil {
+			return a
+		}
+	}
+	if n.param != nil {
+		if a := n.param.find(segments[1:], method, pathMatched); a != nil {
+			return a
+		}
+	}
+	if n.catchAll != nil {
+		return n.catchAll.entry(method, pathMatched)
+	}
+	return nil
+}
+
+func (n *_RESTFulRouteNode) entry(method string, pathMatched **_RESTFulRouteNode) *_RESTFulApiEntry {
+	if len(n.entries) == 0 {
+		return nil
+	}
+	if *pathMatched == nil {
+		*pathMatched = n
+	}
+	if a, ok := n.entries[method]; ok {
+		return a
+	}
+	return n.entries[""]
+}
+
+// checkHTTPEntryConflict RESTFul 路由与 AddHTTPHandle 注册的 URL(GET/POST/OPTIONS)路径相同且方法重叠时返回 error;
+// 路径不同但可匹配(如 /user/list 与 /user/:id)时与 gin 一致, URL 优先
+func checkHTTPEntryConflict(urlPath, method string) error {
+	if _, ok := httpEntry[urlPath]; !ok {
+		return nil
+	}
+	switch method {
+	case "", http.MethodGet, http.MethodPost, http.MethodOptions:
+		return fmt.Errorf("[RESTFul] url:%s method:%s conflicts with registered url handle:%s", urlPath, restFulMethodName(method), urlPath)
+	}
+	return nil
+}
+
+// checkRESTFulConflict AddHTTPHandle 注册的 URL 与已注册的 RESTFul 路由冲突时返回 error, 参见 checkHTTPEntryConflict
+func checkRESTFulConflict(urlPath string) error {
+	for _, method := range []string{"", http.MethodGet, http.MethodPost, http.MethodOptions} {
+		key := urlPath
+		if method != "" {
+			key = method + " " + urlPath
+		}
+		if _, ok := restFulHttpEntry[key]; ok {
+			return fmt.Errorf("[RESTFul] url handle:%s conflicts with registered url:%s method:%s", urlPath, urlPath, restFulMethodName(method))
+		}
+	}
+	return nil
+}
+
+func restFulMethodName(method string) string {
+	if method == "" {
+		return "ANY"
+	}
+	return method
+}
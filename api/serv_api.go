@@ -7,8 +7,10 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 
@@ -36,7 +38,8 @@ type (
 	//HTTPLogResponse HTTP处理结果日志内容
 	HTTPLogResponse        func(response interface{}) string
 	httpRequestActionParam struct {
-		Action string `form:"Action" json:"Action" binding:"required"`
+		Action  string `form:"Action" json:"Action" binding:"required"`
+		Version string `form:"Version" json:"Version"` //Action 版本, 参见 AddActionVersion
 	}
 	httpHandleEntry struct {
 		handleFunc            HTTPHandleFunc
@@ -44,6 +47,7 @@ type (
 		logResponse           HTTPLogResponse
 		logger                string
 		httpCodeStatus        string
+		responseType          reflect.Type
 	}
 	// HTTPAuditLog 审核日志记录
 	HTTPAuditLog             func(urlPath string, action string, request *string, response *string, c *gin.Context)
@@ -111,8 +115,11 @@ var (
 	defaultBindErrorResponse2    interface{}
 	EnableMonitorHttpAPI         bool                           //是否开启 API 非预期返回的结果监控上报
 	NotifyHttpAPIWeChatRobot     string                         //上报 Robot 地址
-	ServiceDisabled              bool                           //服务状态是否 Disable 默认值为 false
-	ExcludeInitServiceDisabled   = make(map[string]interface{}) //不受 ServiceDisabled 影响的请求 action 或者 url
+	ServiceDisabled              bool                           //服务状态是否 Disable 默认值为 false, 修改请使用 SetServiceReady, 读取请使用 IsServiceReady
+	ExcludeInitServiceDisabled   = make(map[string]interface{}) //不受 ServiceDisabled 影响的请求 action 或者 url, 修改请使用 AddExcludeServiceDisabled
+	syncServiceDisabled          = sync.RWMutex{}
+	syncExcludeServiceDisabled   = sync.RWMutex{}
+	responseHTTPCodeContextKey   = "landau_response_http_code"
 	serviceTooEarly              = map[string]interface{}{
 		"Code":    425,
 		"Message": "Service Unavailable",
@@ -120,6 +127,9 @@ var (
 )
 
 func (c *httpRequestActionParam) String() string {
+	if c.Version != "" {
+		return fmt.Sprintf(`{"Action":"%s","Version":"%s"}`, c.Action, c.Version)
+	}
 	return fmt.Sprintf(`{"Action":"%s"}`, c.Action)
 }
 
@@ -152,9 +162,30 @@ func init() {
 }
 
 func AddExcludeServiceDisabled(key string) {
+	syncExcludeServiceDisabled.Lock()
+	defer syncExcludeServiceDisabled.Unlock()
 	ExcludeInitServiceDisabled[key] = struct{}{}
 }
 
+// RemoveExcludeServiceDisabled 删除不受 ServiceDisabled 影响的请求 action 或者 url
+func RemoveExcludeServiceDisabled(key string) {
+	syncExcludeServiceDisabled.Lock()
+	defer syncExcludeServiceDisabled.Unlock()
+	delete(ExcludeInitServiceDisabled, key)
+}
+
+// GetExcludeServiceDisabled 返回不受 ServiceDisabled 影响的请求 action 或者 url
+func GetExcludeServiceDisabled() []string {
+	syncExcludeServiceDisabled.RLock()
+	defer syncExcludeServiceDisabled.RUnlock()
+	keys := make([]string, 0, len(ExcludeInitServiceDisabled))
+	for k := range ExcludeInitServiceDisabled {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func SetDefaultBindError(replaced bool, replaceResponse interface{}) {
 	replaceDefaultBindError = replaced
 	defaultBindErrorResponse2 = replaceResponse
@@ -199,9 +230,19 @@ func SetResponseLogAsJSON(responseLogAsJSON bool) {
 	defaultResponseLogAsJSON = responseLogAsJSON
 }
 
-// SetServiceReady 设置 Service Ready Status
+// SetServiceReady 设置 Service Ready Status, 同步更新 gRPC health 状态
 func SetServiceReady(serviceReady bool) {
+	syncServiceDisabled.Lock()
 	ServiceDisabled = !serviceReady
+	syncServiceDisabled.Unlock()
+	refreshGRPCHealth()
+}
+
+// IsServiceReady 返回 Service Ready Status(ServiceDisabled 取反)
+func IsServiceReady() bool {
+	syncServiceDisabled.RLock()
+	defer syncServiceDisabled.RUnlock()
+	return !ServiceDisabled
 }
 
 // SetUnRegisterHandle 设置未注册的Action处理入口
@@ -211,13 +252,20 @@ func SetUnRegisterHandle(handle HTTPHandleFunc) {
 
 // AddHTTPHandle 注册URL处理程序
 func AddHTTPHandle(urlPath string, actionID string, newRequesterParameter HTTPRequestParameter, handleFunc HTTPHandleFunc, logResponse HTTPLogResponse, loggerName string) {
-	h := httpHandleEntry{
+	addHTTPHandleEntry(urlPath, actionID, httpHandleEntry{
 		handleFunc:            handleFunc,
 		newRequesterParameter: newRequesterParameter,
 		logResponse:           logResponse,
 		logger:                loggerName,
-	}
+	})
+}
+
+// addHTTPHandleEntry 注册 URL/Action 处理程序, URL 与已注册的 RESTFul 路由冲突时 panic
+func addHTTPHandleEntry(urlPath string, actionID string, h httpHandleEntry) {
 	if urlPath != "" {
+		if err := checkRESTFulConflict(urlPath); err != nil {
+			panic(err.Error())
+		}
 		httpEntry[urlPath] = h
 	}
 	if actionID != "" {
@@ -231,19 +279,24 @@ func AddHTTPHandle2(urlPath string, actionID string, newRequesterParameter HTTPR
 }
 
 func AddHTTPHandle3(urlPath string, actionID string, newRequesterParameter HTTPRequestParameter, handleFunc HTTPHandleFunc, httpCodeStatus string) {
-	h := httpHandleEntry{
+	addHTTPHandleEntry(urlPath, actionID, httpHandleEntry{
 		handleFunc:            handleFunc,
 		newRequesterParameter: newRequesterParameter,
 		logResponse:           defaultLogResponse,
 		logger:                defaultAPILogger,
 		httpCodeStatus:        httpCodeStatus,
-	}
-	if urlPath != "" {
-		httpEntry[urlPath] = h
-	}
-	if actionID != "" {
-		httpActionEntry[actionID] = h
-	}
+	})
+}
+
+// AddHTTPHandle4 注册URL处理程序, responseType 为响应结构体(或其指针)样例, 用于生成 OpenAPI 文档
+func AddHTTPHandle4(urlPath string, actionID string, newRequesterParameter HTTPRequestParameter, handleFunc HTTPHandleFunc, responseType interface{}) {
+	addHTTPHandleEntry(urlPath, actionID, httpHandleEntry{
+		handleFunc:            handleFunc,
+		newRequesterParameter: newRequesterParameter,
+		logResponse:           defaultLogResponse,
+		logger:                defaultAPILogger,
+		responseType:          reflect.TypeOf(responseType),
+	})
 }
 
 // AddUnHtmlEscapeHttpHandle 注册URL处理程序,响应json内容不进行 HTML Escape 处理
@@ -262,78 +315,63 @@ func RegisterHTTPHandle(r *gin.Engine) {
 	for k := range httpEntry {
 		r.GET(k, httpHandleProxy)
 		r.POST(k, httpHandleProxy)
+		r.OPTIONS(k, httpPreflightHandle)
 	}
-	optionResponse := func(c *gin.Context) {
-		if v, ok := c.Request.Header["Origin"]; ok && len(v) > 0 && v[0] != "" {
-			c.Header("Access-Control-Allow-Credentials", "true")
-			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			c.Header("Access-Control-Allow-Origin", v[0])
-			c.Header("Access-Control-Allow-Headers", "Content-Type")
-		}
-		success := gin.H{
-			"Code":    0,
-			"Message": "options success",
-		}
-		c.JSON(http.StatusOK, success)
+	r.NoRoute(noRouteHandle)
+}
+
+// noRouteHandle 依次尝试 RESTFul 路由, CORS preflight 及未注册 Action 处理入口
+func noRouteHandle(c *gin.Context) {
+	if restFullHttpHandleProxy(c) {
+		return
 	}
-	r.NoRoute(func(c *gin.Context) {
-		if c.Request.Method == "OPTIONS" {
-			optionResponse(c)
-			return
-		}
-		start := time.Now()
-		urlPath := c.Request.URL.Path
-		addAccessControlAllowHeader(c)
-		if unRegisterHandle == nil {
-			c.String(http.StatusNotFound, "404 page not found")
-			return
-		}
-		p := &httpRequestActionParam{}
-		isPostMethod := c.Request.Method == "POST"
-		isBindingComplex := isPostBindingComplex(urlPath, "")
-		_, _ = bindParams(c, &p, isPostMethod, isBindingComplex)
-		if _isCheckServiceNotReady(p.Action, urlPath) {
-			c.JSON(http.StatusTooEarly, serviceTooEarly)
-			return
-		}
-		if response, isDeny := isACLDeny(urlPath, p.Action, c); isDeny {
-			c.JSON(http.StatusOK, response)
-			return
-		}
-		response, requestParamLog := unRegisterHandle(c, p)
+	if c.Request.Method == "OPTIONS" {
+		corsPreflight(c, c.Request.URL.Path, c.Query("Action"))
+		return
+	}
+	start := time.Now()
+	requestID := prepareRequestID(c)
+	urlPath := c.Request.URL.Path
+	addAccessControlAllowHeader(c, urlPath, c.Query("Action"))
+	if unRegisterHandle == nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+	p := &httpRequestActionParam{}
+	isPostMethod := c.Request.Method == "POST"
+	isBindingComplex := isPostBindingComplex(urlPath, "")
+	_, _ = bindParams(c, &p, isPostMethod, isBindingComplex)
+	if _isCheckServiceNotReady(p.Action, urlPath) {
+		c.JSON(http.StatusTooEarly, serviceTooEarly)
+		return
+	}
+	if response, isDeny := isACLDeny(urlPath, p.Action, c); isDeny {
 		c.JSON(http.StatusOK, response)
-		_doMonitorAPIResult(response)
-		strCustomLogTag := ""
-		if customAPILogTag && httpCustomLogTag != nil {
-			strCustomLogTag = httpCustomLogTag(c)
-		}
-		strResponse := ""
-		if defaultResponseLogAsJSON {
-			if v, ok := response.(fmt.Stringer); ok {
-				strResponse = v.String()
-			} else {
-				byteResp, _ := json.Marshal(response)
-				strResponse = string(byteResp)
-			}
+		return
+	}
+	response, requestParamLog := unRegisterHandle(c, p)
+	c.JSON(http.StatusOK, response)
+	_doMonitorAPIResult(response)
+	strCustomLogTag := ""
+	if customAPILogTag && httpCustomLogTag != nil {
+		strCustomLogTag = httpCustomLogTag(c)
+	}
+	strResponse := ""
+	if defaultResponseLogAsJSON {
+		if v, ok := response.(fmt.Stringer); ok {
+			strResponse = v.String()
 		} else {
-			strResponse = fmt.Sprintf("%v", response)
-		}
-		if httpAuditLog != nil {
-			actionName, bizResponse := getHTTPAuditLogContent(urlPath, p, response)
-			httpAuditLog(urlPath, actionName, &requestParamLog, &bizResponse, c)
-		}
-		log.Info2("API", "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v", urlPath, time.Since(start), strCustomLogTag, requestParamLog, defaultLogResponse(strResponse))
-	})
-}
-
-func addAccessControlAllowHeader(c *gin.Context) {
-	if httpAccessControlAllowOrigin {
-		if v, ok := c.Request.Header["Origin"]; ok && len(v) > 0 && v[0] != "" {
-			c.Header("Access-Control-Allow-Credentials", "true")
-			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			c.Header("Access-Control-Allow-Origin", v[0])
+			byteResp, _ := json.Marshal(response)
+			strResponse = string(byteResp)
 		}
+	} else {
+		strResponse = fmt.Sprintf("%v", response)
+	}
+	if httpAuditLog != nil {
+		actionName, bizResponse := getHTTPAuditLogContent(urlPath, p, response)
+		httpAuditLog(urlPath, actionName, &requestParamLog, &bizResponse, c)
 	}
+	log.Info2("API", "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v\tRequestId:%s", urlPath, time.Since(start), strCustomLogTag, requestParamLog, defaultLogResponse(strResponse), requestID)
 }
 
 func isACLDeny(urlPath string, actionID string, c *gin.Context) (interface{}, bool) {
@@ -352,10 +390,23 @@ func isACLDeny(urlPath string, actionID string, c *gin.Context) (interface{}, bo
 func dispatchAction(c *gin.Context, requestParams interface{}) (interface{}, string) {
 	p := requestParams.(*httpRequestActionParam)
 	_traceLastServiceAddress(c)
-	if a, existed := httpActionEntry[p.Action]; existed {
+	if a, version, existed := getActionEntry(c, p); existed {
 		if response, isDeny := isACLDeny("/", p.Action, c); isDeny {
 			return response, ""
 		}
+		if a == nil {
+			return actionVersionNotFoundRsp, p.String()
+		}
+		markActionDeprecation(c, p.Action, version)
+		release, rejected := acquireRateLimit(c, "", p.Action)
+		defer releaseAfterHandle(c, release)
+		if rejected != "" {
+			return rateLimitResponse(rejected), rejected
+		}
+		chain := getMiddlewareChain("/", p.Action)
+		if rsp := chain.beforeBind(c); rsp != nil {
+			return rsp, p.String()
+		}
 		isPostMethod := c.Request.Method == "POST"
 		bizParamStruct := a.newRequesterParameter()
 		isBindingComplex := isPostBindingComplex("", p.Action)
@@ -364,11 +415,22 @@ func dispatchAction(c *gin.Context, requestParams interface{}) (interface{}, str
 			if _isCheckServiceNotReady(p.Action, "") {
 				return serviceTooEarly, p.String()
 			}
-			rsp, reqStr := a.handleFunc(c, param)
+			if rsp := beginIdempotency(c, "", p.Action); rsp != nil {
+				return rsp, p.String()
+			}
+			if rsp := chain.afterBind(c, param); rsp != nil {
+				return rsp, p.String()
+			}
+			rsp, reqStr := callHandleWithCache(c, "", p.Action, "", param, func(c *gin.Context) (interface{}, string) {
+				return callHandleWithTimeout(c, "", p.Action, func(c *gin.Context) (interface{}, string) { return a.handleFunc(c, param) })
+			})
 			_doMonitorAPIResult(rsp)
-			return rsp, reqStr
+			if isHandleTimeout(c) { //超时的 handle 仍在执行, 不执行 AfterHandle
+				return rsp, reqStr
+			}
+			return chain.afterHandle(c, param, rsp), reqStr
 		}
-		return gin.H{"Code": 230, "Message": fmt.Sprintf("Bind params error [%v]", bindError)}, p.String()
+		return newBindErrorResponse(c, param, bindError), p.String()
 	}
 	if unRegisterHandle != nil {
 		if response, isDeny := isACLDeny("/", p.Action, c); isDeny {
@@ -396,11 +458,13 @@ func bindParams(c *gin.Context, param interface{}, isPostMethod bool, isBindingC
 	return p, bindError
 }
 
-func bindParamsRestful(c *gin.Context, param interface{}, isPostMethod bool, isBindingComplex bool, id, method string) (interface{}, error) {
+func bindParamsRestful(c *gin.Context, param interface{}, isPostMethod bool, isBindingComplex bool, pathParams map[string]string, id, method string) (interface{}, error) {
 	var bindError error
 	v := reflect.ValueOf(param)
 	p := reflect.Indirect(v).Interface()
-	setRestFulKeys(p, id, method)
+	if bindError = setRestFulKeys(p, pathParams, id, method); bindError != nil {
+		return p, bindError
+	}
 	if isPostMethod {
 		bindError = bindPost(p, c, isBindingComplex)
 	} else {
@@ -409,7 +473,12 @@ func bindParamsRestful(c *gin.Context, param interface{}, isPostMethod bool, isB
 	return p, bindError
 }
 func httpHandleProxy(c *gin.Context) {
+	data.InFlightAdd(data.InFlightHTTP)
+	defer data.InFlightDone(data.InFlightHTTP)
+	defer waitHandleTimeout(c)
+	defer abortIdempotency(c)
 	start := time.Now()
+	requestID := prepareRequestID(c)
 	urlPath := c.Request.URL.Path
 	isPostMethod := c.Request.Method == "POST"
 	_traceLastServiceAddress(c)
@@ -428,15 +497,15 @@ func httpHandleProxy(c *gin.Context) {
 				if responseJSONPEnable {
 					jsonpCallback = c.DefaultQuery(responseJSONPCallbackQueryName, "")
 				}
-				addAccessControlAllowHeader(c)
+				addAccessControlAllowHeader(c, urlPath, "")
 				httpCode := http.StatusOK
 				if a.httpCodeStatus != "" {
 					httpCode = getHttpStatusCodeFromResponseObject(response, a.httpCodeStatus, http.StatusOK)
 				}
-				if jsonpCallback == "" {
-					c.JSON(httpCode, response)
-				} else {
+				if jsonpCallback != "" {
 					c.Render(httpCode, render.JsonpJSON{Callback: jsonpCallback, Data: response})
+				} else if !renderNegotiated(c, httpCode, response) {
+					c.JSON(httpCode, response)
 				}
 				strCustomLogTag := ""
 				if customAPILogTag && httpCustomLogTag != nil {
@@ -453,38 +522,72 @@ func httpHandleProxy(c *gin.Context) {
 				} else {
 					strResponse = fmt.Sprintf("%v", response)
 				}
-				log.Info2(a.logger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v", urlPath, time.Since(start), strCustomLogTag, "{}", strResponse)
+				log.Info2(a.logger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v\tRequestId:%s", urlPath, time.Since(start), strCustomLogTag, "{}", strResponse, requestID)
 				extraLabelValues := prometheus.GetExtraLabelValue("", urlPath, c.Request, response, c)
-				prometheus.UpdateApiMetric(getCodeFromInterface(response), "", start, c.Request, urlPath, extraLabelValues)
+				prometheus.UpdateApiCacheMetric(getCodeFromInterface(response), "", start, c.Request, urlPath, getResponseCacheState(c), extraLabelValues)
 				return
 			}
 		}
 		prepareRequestParam(c, isPostMethod)
-		var response interface{}
+		var chain middlewareChain
+		if urlPath != "/" { //Action 请求由 dispatchAction 执行中间件
+			chain = getMiddlewareChain(urlPath, "")
+		}
 		requestParamLog := ""
 		bizParamStruct := a.newRequesterParameter()
-		param, bindError := bindParams(c, &bizParamStruct, isPostMethod, isBindingComplex)
-		if bindError == nil {
-			if urlPath != "/" {
-				if _isCheckServiceNotReady("", urlPath) {
-					c.JSON(http.StatusTooEarly, serviceTooEarly)
-					return
+		release, rejected := func() {}, ""
+		if urlPath != "/" { //Action 请求由 dispatchAction 执行限流
+			release, rejected = acquireRateLimit(c, urlPath, "")
+			requestParamLog = rejected
+		}
+		defer releaseAfterHandle(c, release)
+		param, response := bizParamStruct, rateLimitResponse(rejected)
+		if response == nil {
+			response = chain.beforeBind(c)
+		}
+		if response == nil {
+			var bindError error
+			param, bindError = bindParams(c, &bizParamStruct, isPostMethod, isBindingComplex)
+			if bindError == nil {
+				if urlPath != "/" {
+					if _isCheckServiceNotReady("", urlPath) {
+						c.JSON(http.StatusTooEarly, serviceTooEarly)
+						return
+					}
+					response = beginIdempotency(c, urlPath, "")
+				}
+				if response == nil {
+					if response = chain.afterBind(c, param); response == nil {
+						if urlPath == "/" { //Action 请求由 dispatchAction 执行超时控制
+							response, requestParamLog = a.handleFunc(c, param)
+						} else {
+							response, requestParamLog = callHandleWithCache(c, urlPath, "", a.httpCodeStatus, param, func(c *gin.Context) (interface{}, string) {
+								return callHandleWithTimeout(c, urlPath, "", func(c *gin.Context) (interface{}, string) { return a.handleFunc(c, param) })
+							})
+						}
+						_doMonitorAPIResult(response)
+						if !isHandleTimeout(c) { //超时的 handle 仍在执行, 不执行 AfterHandle
+							response = chain.afterHandle(c, param, response)
+						}
+					}
 				}
-			}
-			response, requestParamLog = a.handleFunc(c, param)
-			_doMonitorAPIResult(response)
-		} else {
-			if replaceDefaultBindError {
-				response = defaultBindErrorResponse2
 			} else {
-				response = gin.H{"Code": 230, "Message": fmt.Sprintf("Bind params error [%v]", bindError)}
+				if replaceDefaultBindError {
+					response = defaultBindErrorResponse2
+				} else {
+					response = newBindErrorResponse(c, param, bindError)
+				}
 			}
 		}
 		jsonpCallback := ""
 		if responseJSONPEnable {
 			jsonpCallback = c.DefaultQuery(responseJSONPCallbackQueryName, "")
 		}
-		addAccessControlAllowHeader(c)
+		corsAction := ""
+		if urlPath == "/" {
+			corsAction = getActionFromInterface(param)
+		}
+		addAccessControlAllowHeader(c, urlPath, corsAction)
 		jsonEscapeHtml := true
 		if urlPath == "/" {
 			if p, ok := param.(*httpRequestActionParam); ok {
@@ -501,15 +604,17 @@ func httpHandleProxy(c *gin.Context) {
 		if a.httpCodeStatus != "" {
 			httpCode = getHttpStatusCodeFromResponseObject(response, a.httpCodeStatus, http.StatusOK)
 		}
-		if jsonpCallback == "" {
+		httpCode = getResponseHTTPCode(c, httpCode)
+		completeIdempotency(c, httpCode, response)
+		if jsonpCallback != "" {
+			c.Render(httpCode, render.JsonpJSON{Callback: jsonpCallback, Data: response})
+		} else if !renderNegotiated(c, httpCode, response) {
 			if jsonEscapeHtml {
 				c.JSON(httpCode, response)
 			} else {
 				noEscapeHtmlResponse := UnHtmlEscapeJsonResponse{Response: response}
 				c.Render(httpCode, noEscapeHtmlResponse)
 			}
-		} else {
-			c.Render(httpCode, render.JsonpJSON{Callback: jsonpCallback, Data: response})
 		}
 		strCustomLogTag := ""
 		if customAPILogTag && httpCustomLogTag != nil {
@@ -538,7 +643,7 @@ func httpHandleProxy(c *gin.Context) {
 		urlLogResponse, apiLogger := a.logResponse, a.logger
 		if urlPath == "/" {
 			if p, ok := param.(*httpRequestActionParam); ok {
-				if actionEntry, actionOK := httpActionEntry[p.Action]; actionOK {
+				if actionEntry, _, _ := getActionEntry(c, p); actionEntry != nil {
 					urlLogResponse = actionEntry.logResponse
 					if actionEntry.logger != "" {
 						apiLogger = actionEntry.logger
@@ -546,10 +651,10 @@ func httpHandleProxy(c *gin.Context) {
 				}
 			}
 		}
-		log.Info2(apiLogger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v", urlPath, time.Since(start), strCustomLogTag, requestParamLog, urlLogResponse(strResponse))
+		log.Info2(apiLogger, "[%s]\t[%s]\t%s\tRequest:%s\tResponse:%v\tRequestId:%s", urlPath, time.Since(start), strCustomLogTag, requestParamLog, urlLogResponse(strResponse), requestID)
 		pAction := getActionFromInterface(param)
 		extraLabelValues := prometheus.GetExtraLabelValue(pAction, urlPath, c.Request, response, c)
-		prometheus.UpdateApiMetric(getCodeFromInterface(response), pAction, start, c.Request, "", extraLabelValues)
+		prometheus.UpdateApiCacheMetric(getCodeFromInterface(response), pAction, start, c.Request, "", getResponseCacheState(c), extraLabelValues)
 	}
 }
 
@@ -693,9 +798,11 @@ func _doMonitorAPIResult(rsp interface{}) {
 }
 
 func _isCheckServiceNotReady(action, url string) bool {
-	if !ServiceDisabled {
+	if IsServiceReady() {
 		return false
 	}
+	syncExcludeServiceDisabled.RLock()
+	defer syncExcludeServiceDisabled.RUnlock()
 	if action != "" {
 		if _, ok := ExcludeInitServiceDisabled[action]; ok {
 			return false
@@ -708,3 +815,16 @@ func _isCheckServiceNotReady(action, url string) bool {
 	}
 	return true
 }
+
+// setResponseHTTPCode 框架拦截请求(限流,幂等重放等)时指定应答 HTTP 状态码
+func setResponseHTTPCode(c *gin.Context, httpCode int) {
+	c.Set(responseHTTPCodeContextKey, httpCode)
+}
+
+// getResponseHTTPCode 返回 setResponseHTTPCode 指定的 HTTP 状态码, 未指定时返回 httpCode
+func getResponseHTTPCode(c *gin.Context, httpCode int) int {
+	if v := c.GetInt(responseHTTPCodeContextKey); v > 0 {
+		return v
+	}
+	return httpCode
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/data"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/prometheus"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	_HandleTimeoutCall struct {
+		done     chan struct{}
+		cancel   context.CancelFunc
+		panicked interface{}
+		release  []func() //handle 结束后执行, 如释放限流并发配额
+	}
+	//_HandleBufferWriter handle 使用的独立 writer, 缓存请求头及应答内容; 按时完成时复制到原请求, 超时后丢弃
+	_HandleBufferWriter struct {
+		header  http.Header
+		status  int
+		written bool
+		body    bytes.Buffer
+	}
+)
+
+const (
+	handleTimeoutContextKey = "landau_handle_timeout"
+)
+
+var (
+	globalHandleTimeout     time.Duration
+	urlHandleTimeout        = make(map[string]time.Duration) //key: url path(RESTFul 为注册的 url 模板)
+	actionHandleTimeout     = make(map[string]time.Duration) //key: action
+	syncHandleTimeout       = sync.RWMutex{}
+	defaultHandleTimeoutRsp interface{}
+	serviceHandleTimeout    = gin.H{
+		"Code":    504,
+		"Message": "Gateway Timeout",
+	}
+)
+
+// SetHandleTimeout 设置全局处理超时时间, 0 不限制(默认)
+func SetHandleTimeout(timeout time.Duration) {
+	syncHandleTimeout.Lock()
+	defer syncHandleTimeout.Unlock()
+	globalHandleTimeout = timeout
+}
+
+// SetURLHandleTimeout 设置 URL 处理超时时间, 优先于全局设置, 0 表示不限制; RESTFul 入口使用注册时的 url 模板(如 /user/:id)
+func SetURLHandleTimeout(urlPath string, timeout time.Duration) {
+	syncHandleTimeout.Lock()
+	defer syncHandleTimeout.Unlock()
+	urlHandleTimeout[urlPath] = timeout
+}
+
+// SetActionHandleTimeout 设置 Action 处理超时时间, 优先于全局设置, 0 表示不限制
+func SetActionHandleTimeout(action string, timeout time.Duration) {
+	syncHandleTimeout.Lock()
+	defer syncHandleTimeout.Unlock()
+	actionHandleTimeout[action] = timeout
+}
+
+// SetHandleTimeoutResponse 设置处理超时时的应答内容, HTTP 状态码为 504, 缺省 {"Code":504,"Message":"Gateway Timeout"}
+func SetHandleTimeoutResponse(response interface{}) {
+	defaultHandleTimeoutRsp = response
+}
+
+// GetContext 返回当前请求的 context, 设置了处理超时时携带 deadline, 超时后被取消;
+// 可以传递给 data.Database.WithContext 及 data.SetHTTPContext
+func GetContext(c *gin.Context) context.Context {
+	return c.Request.Context()
+}
+
+func getHandleTimeout(urlPath, action string) time.Duration {
+	syncHandleTimeout.RLock()
+	defer syncHandleTimeout.RUnlock()
+	if action != "" {
+		if t, ok := actionHandleTimeout[action]; ok {
+			return t
+		}
+	}
+	if urlPath != "" {
+		if t, ok := urlHandleTimeout[urlPath]; ok {
+			return t
+		}
+	}
+	return globalHandleTimeout
+}
+
+// callHandleWithTimeout 设置了处理超时时在新的 goroutine 中以 c.Copy() 执行 handle, 超时后取消其 Request.Context() 并返回超时应答;
+// handle 按时完成时其设置的应答头, 应答内容及 c.Set 的值复制回 c. 超时的 handle 可能继续执行, 由 waitHandleTimeout 在后台等待其结束
+func callHandleWithTimeout(c *gin.Context, urlPath, action string, handle func(c *gin.Context) (interface{}, string)) (interface{}, string) {
+	timeout := getHandleTimeout(urlPath, action)
+	if timeout <= 0 {
+		return handle(c)
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	cp := c.Copy()
+	cp.Request = c.Request.WithContext(ctx)
+	writer := &_HandleBufferWriter{header: c.Writer.Header().Clone()}
+	cp.Writer = writer
+	call := &_HandleTimeoutCall{done: make(chan struct{}), cancel: cancel}
+	var response interface{}
+	var requestLog string
+	go func() {
+		defer close(call.done)
+		defer func() {
+			if p := recover(); p != nil {
+				call.panicked = p
+			}
+		}()
+		response, requestLog = handle(cp)
+	}()
+	select {
+	case <-call.done:
+		cancel()
+		if call.panicked != nil {
+			panic(call.panicked)
+		}
+		writer.copyTo(c.Writer)
+		for k, v := range cp.Keys {
+			c.Set(k, v)
+		}
+		return response, requestLog
+	case <-ctx.Done():
+	}
+	c.Set(handleTimeoutContextKey, call)
+	setResponseHTTPCode(c, http.StatusGatewayTimeout)
+	prometheus.UpdateHandleTimeoutMetric(action, urlPath)
+	if defaultHandleTimeoutRsp != nil {
+		return defaultHandleTimeoutRsp, fmt.Sprintf("HandleTimeout timeout:%s error:%v", timeout, ctx.Err())
+	}
+	return serviceHandleTimeout, fmt.Sprintf("HandleTimeout timeout:%s error:%v", timeout, ctx.Err())
+}
+
+// isHandleTimeout 本次请求是否处理超时
+func isHandleTimeout(c *gin.Context) bool {
+	_, ok := c.Get(handleTimeoutContextKey)
+	return ok
+}
+
+// releaseAfterHandle 处理超时时在超时的 handle 结束后执行 release(如释放限流并发配额), 否则立即执行;
+// 须在 waitHandleTimeout 之前执行(defer 时在其之后注册)
+func releaseAfterHandle(c *gin.Context, release func()) {
+	if v, ok := c.Get(handleTimeoutContextKey); ok {
+		call := v.(*_HandleTimeoutCall)
+		call.release = append(call.release, release)
+		return
+	}
+	release()
+}
+
+// waitHandleTimeout 处理超时时在后台等待超时的 handle 结束, 之后执行 releaseAfterHandle 登记的函数; 等待期间计入 HTTP 处理中请求数
+func waitHandleTimeout(c *gin.Context) {
+	v, ok := c.Get(handleTimeoutContextKey)
+	if !ok {
+		return
+	}
+	call := v.(*_HandleTimeoutCall)
+	urlPath, requestID := c.Request.URL.Path, GetRequestID(c)
+	data.InFlightAdd(data.InFlightHTTP)
+	go func() {
+		defer data.InFlightDone(data.InFlightHTTP)
+		start := time.Now()
+		<-call.done
+		call.cancel()
+		for _, release := range call.release {
+			release()
+		}
+		if call.panicked != nil {
+			log.Error2(defaultAPILogger, "[%s]\t[HandleTimeout] wait:%s panic:%v\tRequestId:%s", urlPath, time.Since(start), call.panicked, requestID)
+		}
+	}()
+}
+
+// copyTo 将 handle 设置的应答头, 状态码及应答内容复制到 dst
+func (w *_HandleBufferWriter) copyTo(dst gin.ResponseWriter) {
+	header := dst.Header()
+	for k := range header {
+		if _, ok := w.header[k]; !ok {
+			delete(header, k)
+		}
+	}
+	for k, v := range w.header {
+		header[k] = v
+	}
+	if w.status != 0 {
+		dst.WriteHeader(w.status)
+	}
+	if w.body.Len() > 0 {
+		_, _ = dst.Write(w.body.Bytes())
+	} else if w.written {
+		dst.WriteHeaderNow()
+	}
+}
+
+func (w *_HandleBufferWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *_HandleBufferWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *_HandleBufferWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *_HandleBufferWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *_HandleBufferWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *_HandleBufferWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *_HandleBufferWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *_HandleBufferWriter) Written() bool {
+	return w.written
+}
+
+func (w *_HandleBufferWriter) Flush() {}
+
+func (w *_HandleBufferWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("hijack is not supported in handle with timeout")
+}
+
+func (w *_HandleBufferWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func (w *_HandleBufferWriter) Pusher() http.Pusher {
+	return nil
+}
@@ -43,7 +43,8 @@ type (
 		driverExtendDSNProperty  map[string]interface{}
 		customLogSQL             func(string) string
 		txOptions                *sql.TxOptions
-		timeout                  int //建立连接的超时时间, 默认3 秒
+		timeout                  int             //建立连接的超时时间, 默认3 秒
+		ctx                      context.Context //执行 SQL 使用的 context, 参见 WithContext
 	}
 	_TxWrap struct {
 		start           time.Time
@@ -230,6 +231,20 @@ func SetDatabaseTimeout(timeout int) DatabaseOptionFunc {
 	}
 }
 
+// WithContext 返回使用 ctx 执行 SQL 的 Database 副本, ctx 取消或超时时正在执行的 SQL 及事务被中止, 如 db.WithContext(api.GetContext(c)).Gets(...)
+func (c *Database) WithContext(ctx context.Context) *Database {
+	db := *c
+	db.ctx = ctx
+	return &db
+}
+
+func (c *Database) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // GetDB 返回sqlx.DB 对象
 func (c *Database) GetDB() (*sqlx.DB, error) {
 	if conn, ok := dbConnectionPool.Load(c.dbConnection); ok {
@@ -389,9 +404,9 @@ func (c *Database) query(dbModel interface{}, strSQL string, isGetOne bool, logA
 		return 0, err
 	}
 	if isGetOne {
-		err = db.Get(dbModel, strSQL, args...)
+		err = db.GetContext(c.context(), dbModel, strSQL, args...)
 	} else {
-		err = db.Select(dbModel, strSQL, args...)
+		err = db.SelectContext(c.context(), dbModel, strSQL, args...)
 	}
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -458,7 +473,7 @@ func (c *Database) Exec2(strSQL string, logArgs func(args ...interface{}) string
 		c.checkError(err)
 		return 0, err
 	}
-	result, execError := db.Exec(strSQL, args...)
+	result, execError := db.ExecContext(c.context(), strSQL, args...)
 	if execError != nil {
 		log.Error2(c.logger, "[SQL] [%s]\t[%s]\tArgs [%v]\tError:[%v]", time.Since(start), c.getLogSQL(strSQL), _getArgsLog(logArgs, args...), execError)
 		c.checkError(execError)
@@ -491,7 +506,7 @@ func (c *Database) Insert2(strSQL string, logArgs func(args ...interface{}) stri
 		c.checkError(err)
 		return 0, err
 	}
-	result, execError := db.Exec(strSQL, args...)
+	result, execError := db.ExecContext(c.context(), strSQL, args...)
 	if execError != nil {
 		log.Error2(c.logger, "[SQL] [%s]\t[%s]\tArgs [%v]\tError:[%v]", time.Since(start), c.getLogSQL(strSQL), _getArgsLog(logArgs, args...), execError)
 		c.checkError(execError)
@@ -520,7 +535,7 @@ func (c *Database) ExecTx(bizFunc TxExecute) error {
 		c.checkError(err)
 		return err
 	}
-	tx, err := db.BeginTx(context.Background(), c.txOptions)
+	tx, err := db.BeginTx(c.context(), c.txOptions)
 	if err != nil {
 		log.Error2(c.logger, "[SQL ExecTx] [%s]\t Begin Tx Error:[%v]", time.Since(start), err)
 		c.checkError(err)
@@ -650,7 +665,7 @@ func (c *_TxWrap) Exec(strSQL string, args ...interface{}) (int, int, error) {
 	defer func() {
 		c.execSQLSequence++
 	}()
-	result, err := c.tx.Exec(strSQL, args...)
+	result, err := c.tx.ExecContext(c.db.context(), strSQL, args...)
 	if err != nil {
 		log.Error2(c.db.logger, "[SQL ExecTx] [%p] [Execute SQL Sequence:%d] [%s] [%s] Args [%v] Error:[%v]", c, c.execSQLSequence, time.Since(start), strSQL, args, err)
 		return 0, 0, err
@@ -674,7 +689,7 @@ func (c *_TxWrap) ScanGet(strSQL string, dest ...interface{}) (int, error) {
 	defer func() {
 		c.execSQLSequence++
 	}()
-	result := c.tx.QueryRow(strSQL)
+	result := c.tx.QueryRowContext(c.db.context(), strSQL)
 	err := result.Err()
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -706,7 +721,7 @@ func (c *_TxWrap) Get(dbModel interface{}, strSQL string, args ...interface{}) (
 	defer func() {
 		c.execSQLSequence++
 	}()
-	result, err := c.tx.Query(strSQL, args...)
+	result, err := c.tx.QueryContext(c.db.context(), strSQL, args...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Info2(c.db.logger, "[SQL ExecTx] [%p] [Execute SQL Sequence:%d] [%s]\t[%s]\tArgs [%v]\t[row count=0]", c, c.execSQLSequence, time.Since(start), strSQL, args)
@@ -801,7 +816,7 @@ func (c *_TxWrap) Gets(dbModel interface{}, strSQL string, args ...interface{})
 	defer func() {
 		c.execSQLSequence++
 	}()
-	result, err := c.tx.Query(strSQL, args...)
+	result, err := c.tx.QueryContext(c.db.context(), strSQL, args...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Info2(c.db.logger, "[SQL ExecTx] [%p] [Execute SQL Sequence:%d] [%s]\t[%s]\tArgs [%v]\t[row count=0]", c, c.execSQLSequence, time.Since(start), strSQL, args)
@@ -4,13 +4,16 @@ import (
 	"context"
 	"fmt"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"reflect"
+	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/NeilXu2017/landau/log"
 	"github.com/NeilXu2017/landau/util"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 type (
@@ -25,8 +28,15 @@ type (
 	}
 )
 
+const (
+	// GRPCAddressScheme keepalive 服务地址前缀, 以此开头的地址使用 grpc_health_v1 检查健康状态
+	GRPCAddressScheme = "grpc://"
+)
+
 var (
+	GRPCHealthCheckDialOptions       = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())} //grpc_health_v1 健康检查连接参数
 	defaultGRPCMaxReceiveMessageSize = 32 * 1024 * 1024
+	grpcHealthCheckMethod            = "/grpc.health.v1.Health/Check"
 	defaultGRPCLogger                = "main"
 	defaultGRPCTimeout               = 15
 	defaultGRPCResponseShowDetail    = false
@@ -61,8 +71,13 @@ func NewGRPCCaller2(address string, newGRPCClient NewGRPCClient) *GRPCService {
 	return NewGRPCCaller(address, newGRPCClient, defaultGRPCMaxReceiveMessageSize, defaultGRPCLogger, defaultGRPCLogResponse)
 }
 
-// CallGRPCService 请求gRPC 服务接口,requestParam 必须是指针类型
+// CallGRPCService 请求gRPC 服务接口,requestParam 必须是指针类型; 不传递 request id, 在请求处理中调用时应使用 CallGRPCServiceContext(c, ...)
 func (c *GRPCService) CallGRPCService(serviceName string, requestParam interface{}, timeout int) (interface{}, error) {
+	return c.CallGRPCServiceContext(context.Background(), serviceName, requestParam, timeout)
+}
+
+// CallGRPCServiceContext 请求gRPC 服务接口,requestParam 必须是指针类型, ctx 携带的 request id 通过 metadata x-request-id 传递
+func (c *GRPCService) CallGRPCServiceContext(ctx context.Context, serviceName string, requestParam interface{}, timeout int) (interface{}, error) {
 	start := time.Now()
 	defer func() {
 		if err := recover(); err != nil {
@@ -77,8 +92,11 @@ func (c *GRPCService) CallGRPCService(serviceName string, requestParam interface
 	}
 	defer conn.Close()
 	client := c.newGRPCClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
 	defer cancel()
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, requestID)
+	}
 	v := reflect.ValueOf(client)
 	m := v.MethodByName(serviceName)
 	params := make([]reflect.Value, 3)
@@ -110,3 +128,33 @@ func (c *GRPCService) CallGRPCService(serviceName string, requestParam interface
 func (c *GRPCService) CallGRPCService2(serviceName string, requestParam interface{}) (interface{}, error) {
 	return c.CallGRPCService(serviceName, requestParam, defaultGRPCTimeout)
 }
+
+// GetGRPCServiceAddrByName 获取 keepalive 中 gRPC 服务(地址以 grpc:// 开头)的可用地址, 返回地址不含 grpc:// 前缀
+func GetGRPCServiceAddrByName(serviceName string) (string, bool) {
+	addr, isPrimary := GetServiceAddrByName(serviceName)
+	return strings.TrimPrefix(addr, GRPCAddressScheme), isPrimary
+}
+
+// grpcHealthChecking 使用 grpc_health_v1 检查 gRPC 服务整体状态, 返回 1 SERVING, 0 其他
+func grpcHealthChecking(addr string) int {
+	start, healthStatus := time.Now(), 0
+	address := util.AddrConvert(strings.TrimPrefix(addr, GRPCAddressScheme), util.IPV6Bracket)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(HealthCheckTimeout)*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, address, GRPCHealthCheckDialOptions...)
+	if err != nil {
+		log.Error2("health_checker", "[GRPC]\t[%s]\t[%s]\tRequest:%s\tDial Error:%v", time.Since(start), grpcHealthCheckMethod, address, err)
+		return healthStatus
+	}
+	defer conn.Close()
+	rsp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err == nil && rsp.GetStatus() == grpc_health_v1.HealthCheckResponse_SERVING {
+		healthStatus = 1
+	}
+	if err != nil {
+		log.Error2("health_checker", "[GRPC]\t[%s]\t[%s]\tRequest:%s\tResponse:\tError:%v", time.Since(start), grpcHealthCheckMethod, address, err)
+	} else {
+		log.Info2("health_checker", "[GRPC]\t[%s]\t[%s]\tRequest:%s\tResponse:%s", time.Since(start), grpcHealthCheckMethod, address, rsp.GetStatus())
+	}
+	return healthStatus
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// HealthChecker 依赖检查函数, 返回 nil 表示依赖可用
+	HealthChecker func(ctx context.Context) error
+	// HealthProbeResponse liveness/readiness 检查结果
+	HealthProbeResponse struct {
+		Status string                       `json:"status"`           //UP 或 DOWN
+		Reason string                       `json:"reason,omitempty"` //DOWN 的原因
+		Checks map[string]HealthCheckResult `json:"checks,omitempty"` //key: checker name
+	}
+	// HealthCheckResult 单个 checker 检查结果
+	HealthCheckResult struct {
+		Status   string `json:"status"`          //UP 或 DOWN
+		Duration string `json:"duration"`        //检查耗时
+		Error    string `json:"error,omitempty"` //检查失败原因
+	}
+	// ConnectionChecker 可以报告连接状态的对象, 如 RabbitMQProducer, RabbitMQConsumer
+	ConnectionChecker interface {
+		IsConnected() bool
+	}
+	_HealthCheckerEntry struct {
+		name    string
+		timeout time.Duration
+		checker HealthChecker
+	}
+)
+
+const (
+	// HealthStatusUp 检查通过
+	HealthStatusUp = "UP"
+	// HealthStatusDown 检查失败
+	HealthStatusDown = "DOWN"
+)
+
+var (
+	DefaultHealthCheckerTimeout = 3 * time.Second //checker 未设置超时时间时使用
+	livenessCheckers            []_HealthCheckerEntry
+	readinessCheckers           []_HealthCheckerEntry
+	syncHealthCheckers          = sync.RWMutex{}
+)
+
+// RegisterLivenessChecker 注册 liveness checker, 同名 checker 被替换. timeout<=0 使用 DefaultHealthCheckerTimeout
+func RegisterLivenessChecker(name string, timeout time.Duration, checker HealthChecker) {
+	syncHealthCheckers.Lock()
+	defer syncHealthCheckers.Unlock()
+	livenessCheckers = addHealthChecker(livenessCheckers, name, timeout, checker)
+}
+
+// RegisterReadinessChecker 注册 readiness checker, 同名 checker 被替换. timeout<=0 使用 DefaultHealthCheckerTimeout
+func RegisterReadinessChecker(name string, timeout time.Duration, checker HealthChecker) {
+	syncHealthCheckers.Lock()
+	defer syncHealthCheckers.Unlock()
+	readinessCheckers = addHealthChecker(readinessCheckers, name, timeout, checker)
+}
+
+func addHealthChecker(entries []_HealthCheckerEntry, name string, timeout time.Duration, checker HealthChecker) []_HealthCheckerEntry {
+	e := _HealthCheckerEntry{name: name, timeout: timeout, checker: checker}
+	for i := range entries {
+		if entries[i].name == name {
+			entries[i] = e
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+// CheckLiveness 执行所有 liveness checker
+func CheckLiveness(ctx context.Context) (HealthProbeResponse, bool) {
+	syncHealthCheckers.RLock()
+	entries := append([]_HealthCheckerEntry(nil), livenessCheckers...)
+	syncHealthCheckers.RUnlock()
+	return runHealthCheckers(ctx, entries)
+}
+
+// CheckReadiness 执行所有 readiness checker
+func CheckReadiness(ctx context.Context) (HealthProbeResponse, bool) {
+	syncHealthCheckers.RLock()
+	entries := append([]_HealthCheckerEntry(nil), readinessCheckers...)
+	syncHealthCheckers.RUnlock()
+	return runHealthCheckers(ctx, entries)
+}
+
+func runHealthCheckers(ctx context.Context, entries []_HealthCheckerEntry) (HealthProbeResponse, bool) {
+	rsp := HealthProbeResponse{Status: HealthStatusUp}
+	if len(entries) == 0 {
+		return rsp, true
+	}
+	rsp.Checks = make(map[string]HealthCheckResult, len(entries))
+	wg, locker := sync.WaitGroup{}, sync.Mutex{}
+	for _, e := range entries {
+		wg.Add(1)
+		go func(e _HealthCheckerEntry) {
+			defer wg.Done()
+			start := time.Now()
+			err := runHealthChecker(ctx, e)
+			r := HealthCheckResult{Status: HealthStatusUp, Duration: time.Since(start).String()}
+			if err != nil {
+				r.Status, r.Error = HealthStatusDown, err.Error()
+			}
+			locker.Lock()
+			rsp.Checks[e.name] = r
+			if err != nil {
+				rsp.Status = HealthStatusDown
+			}
+			locker.Unlock()
+		}(e)
+	}
+	wg.Wait()
+	return rsp, rsp.Status == HealthStatusUp
+}
+
+// runHealthChecker 在超时时间内执行 checker, checker 未响应 ctx 取消时也按超时返回
+func runHealthChecker(ctx context.Context, e _HealthCheckerEntry) (err error) {
+	timeout := e.timeout
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckerTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic:%v", p)
+			}
+		}()
+		done <- e.checker(ctx)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timeout after %s", timeout)
+	}
+}
+
+// NewDatabaseHealthChecker 检查 MySQL 连接
+func NewDatabaseHealthChecker(db *Database) HealthChecker {
+	return func(ctx context.Context) error {
+		conn, err := db.GetDB()
+		if err != nil {
+			return err
+		}
+		return conn.PingContext(ctx)
+	}
+}
+
+// NewRedisHealthChecker 检查 Redis 连接
+func NewRedisHealthChecker(redisDB *RedisDatabase) HealthChecker {
+	return func(_ context.Context) error {
+		client, err := redisDB.GetRedisClient()
+		if client != nil {
+			defer client.Close()
+		}
+		return err
+	}
+}
+
+// NewMongoHealthChecker 检查 MongoDB 连接
+func NewMongoHealthChecker(mgoDB *MongoDatabase) HealthChecker {
+	return func(_ context.Context) error {
+		session, err := mgoDB.GetMgoSession()
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+		return session.Ping()
+	}
+}
+
+// NewConnectionHealthChecker 检查 RabbitMQProducer/RabbitMQConsumer 等对象的连接状态
+func NewConnectionHealthChecker(conn ConnectionChecker) HealthChecker {
+	return func(_ context.Context) error {
+		if !conn.IsConnected() {
+			return errors.New("not connected")
+		}
+		return nil
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"crypto/tls"
 	"encoding/json"
@@ -58,6 +59,7 @@ type (
 		disableAssignSourceIp      bool   //是否指定源IP
 		requestHost                string //设置 request.Host
 		dialTimeout                int    //连接超时时间,默认30
+		ctx                        context.Context
 	}
 	_HttpCookieJar struct {
 		cookies []*http.Cookie
@@ -348,6 +350,14 @@ func SetHTTPDelegatedHTTPRequest(delegatedHTTPRequest *http.Request) HTTPHelperO
 	}
 }
 
+// SetHTTPContext 设置请求 context, 携带的 request id 通过 X-Request-Id 头传递, context 取消或超时时中止请求, 可以直接使用 *gin.Context
+func SetHTTPContext(ctx context.Context) HTTPHelperOptionFunc {
+	return func(c *HTTPHelper) error {
+		c.ctx = ctx
+		return nil
+	}
+}
+
 // SetHTTPPostBody 设置 HTTP postBody 参数
 func SetHTTPPostBody(postBody string) HTTPHelperOptionFunc {
 	return func(c *HTTPHelper) error {
@@ -526,7 +536,7 @@ func (c *HTTPHelper) Call() (string, error) {
 		jar.cookies = c.delegatedHTTPRequest.Cookies()
 		client.Jar = jar
 	}
-	req, err := http.NewRequest(reqMethod, reqURL, bodyReader)
+	req, err := http.NewRequestWithContext(c.context(), reqMethod, reqURL, bodyReader)
 	if err != nil {
 		log.Error2(c.logger, "[HTTP]\t[%s]\tURL:%s\t%s\tError:%v", time.Since(start), reqURL, requestLoggerMsg, err)
 		return "", err
@@ -540,6 +550,7 @@ func (c *HTTPHelper) Call() (string, error) {
 			req.Header.Set(k, v)
 		}
 	}
+	c.setRequestID(req)
 	if c.appendServiceId && ServiceName != "" && ServiceAddress != "" {
 		req.Header.Set(ServiceNameHeadTag, ServiceName)
 		req.Header.Set(ServiceAddressHeadTag, ServiceAddress)
@@ -640,7 +651,7 @@ func (c *HTTPHelper) Upload(fileFieldName string, filePath string) (string, erro
 		return "", err
 	}
 	var r *http.Request
-	if r, err = http.NewRequest("POST", c.url, body); err != nil {
+	if r, err = http.NewRequestWithContext(c.context(), "POST", c.url, body); err != nil {
 		return "", err
 	}
 	r.Header.Set("Content-Type", writer.FormDataContentType())
@@ -650,6 +661,7 @@ func (c *HTTPHelper) Upload(fileFieldName string, filePath string) (string, erro
 			r.Header.Set(k, v)
 		}
 	}
+	c.setRequestID(r)
 	if c.requestHost != "" {
 		r.Host = c.requestHost
 	}
@@ -697,6 +709,29 @@ func (c *HTTPHelper) Upload2(fileFieldName string, filePath string, responseObje
 	return json.Unmarshal([]byte(response), &responseObject)
 }
 
+func (c *HTTPHelper) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// setRequestID 未设置 X-Request-Id 头时, 使用 context 或 delegatedHTTPRequest 的 request id
+func (c *HTTPHelper) setRequestID(req *http.Request) {
+	if req.Header.Get(RequestIDHeader) != "" {
+		return
+	}
+	requestID := RequestIDFromContext(c.ctx)
+	if requestID == "" && c.delegatedHTTPRequest != nil {
+		if requestID = RequestIDFromContext(c.delegatedHTTPRequest.Context()); requestID == "" {
+			requestID = c.delegatedHTTPRequest.Header.Get(RequestIDHeader)
+		}
+	}
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+}
+
 func getEncodedQueryString(params map[string]interface{}) string {
 	strQuery := ""
 	if params != nil {
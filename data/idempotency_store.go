@@ -0,0 +1,141 @@
+package data
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/log"
+	"github.com/go-redis/redis"
+)
+
+type (
+	//IdempotencyRecord 幂等请求首次处理的应答
+	IdempotencyRecord struct {
+		Status      int             //HTTP 状态码
+		Body        json.RawMessage //应答内容(json)
+		Fingerprint string          //请求内容 hash, 相同 key 请求内容不一致时不重放
+	}
+	//IdempotencyStore 幂等记录存储
+	IdempotencyStore interface {
+		//Begin 标记 key 处理中; 返回 started=true 表示由本次请求处理, record 非空表示已有应答可重放, 两者皆否表示处理中
+		Begin(key string, lockTTL time.Duration) (record *IdempotencyRecord, started bool, err error)
+		//Complete 保存应答, ttl 时间内重放
+		Complete(key string, record *IdempotencyRecord, ttl time.Duration) error
+		//Abort 处理异常时清除处理中标记, 允许重试
+		Abort(key string) error
+	}
+	_MemoryIdempotencyStore struct {
+		sync.Mutex
+		records   map[string]*_IdempotencyEntry
+		lastSweep time.Time
+	}
+	_IdempotencyEntry struct {
+		record *IdempotencyRecord //nil 表示处理中
+		expire time.Time
+	}
+	_RedisIdempotencyStore struct {
+		*_RedisSharedClient
+	}
+)
+
+const (
+	redisIdempotencyPending = "-" //Redis 中处理中标记
+)
+
+var (
+	//仍为处理中标记时删除, KEYS[1] ARGV: 处理中标记
+	redisIdempotencyAbortScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('DEL', KEYS[1]) end
+return 0`)
+)
+
+// NewMemoryIdempotencyStore 本地内存幂等记录, 仅本实例有效
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &_MemoryIdempotencyStore{records: make(map[string]*_IdempotencyEntry), lastSweep: time.Now()}
+}
+
+// NewRedisIdempotencyStore Redis 幂等记录, 集群内共享
+func NewRedisIdempotencyStore(db *RedisDatabase) IdempotencyStore {
+	return &_RedisIdempotencyStore{_RedisSharedClient: newRedisSharedClient(db)}
+}
+
+func (c *_MemoryIdempotencyStore) Begin(key string, lockTTL time.Duration) (*IdempotencyRecord, bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	if now.Sub(c.lastSweep) > time.Minute {
+		c.lastSweep = now
+		for k, e := range c.records {
+			if now.After(e.expire) {
+				delete(c.records, k)
+			}
+		}
+	}
+	if e, ok := c.records[key]; ok && now.Before(e.expire) {
+		return e.record, false, nil
+	}
+	c.records[key] = &_IdempotencyEntry{expire: now.Add(lockTTL)}
+	return nil, true, nil
+}
+
+func (c *_MemoryIdempotencyStore) Complete(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+	c.records[key] = &_IdempotencyEntry{record: record, expire: time.Now().Add(ttl)}
+	return nil
+}
+
+func (c *_MemoryIdempotencyStore) Abort(key string) error {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.records[key]; ok && e.record == nil {
+		delete(c.records, key)
+	}
+	return nil
+}
+
+func (c *_RedisIdempotencyStore) Begin(key string, lockTTL time.Duration) (*IdempotencyRecord, bool, error) {
+	client, err := c.getClient()
+	if err != nil {
+		log.Error2(c.db.logger, "[Redis]\t[Idempotency] Key:%s Error:%v", key, err)
+		return nil, false, err
+	}
+	started, err := client.SetNX(key, redisIdempotencyPending, lockTTL).Result()
+	if err != nil || started {
+		return nil, started, err
+	}
+	v, err := client.Get(key).Result()
+	if err == redis.Nil { //处理中标记刚过期
+		return c.Begin(key, lockTTL)
+	}
+	if err != nil || v == redisIdempotencyPending {
+		return nil, false, err
+	}
+	record := &IdempotencyRecord{}
+	if err = json.Unmarshal([]byte(v), record); err != nil {
+		log.Error2(c.db.logger, "[Redis]\t[Idempotency] Key:%s Value:%s Error:%v", key, v, err)
+		return nil, false, err
+	}
+	return record, false, nil
+}
+
+func (c *_RedisIdempotencyStore) Complete(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return client.Set(key, v, ttl).Err()
+}
+
+func (c *_RedisIdempotencyStore) Abort(key string) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	return redisIdempotencyAbortScript.Run(client, []string{key}, redisIdempotencyPending).Err()
+}
@@ -0,0 +1,68 @@
+package data
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	InFlightHTTP = "HTTP" //HTTP 请求
+	InFlightGRPC = "gRPC" //gRPC 请求
+	InFlightMQ   = "MQ"   //MQ 消息
+)
+
+var (
+	inFlightCounters         = sync.Map{} //key: kind value: *int64
+	inFlightWaitPollInterval = 50 * time.Millisecond
+)
+
+func inFlightCounter(kind string) *int64 {
+	if v, ok := inFlightCounters.Load(kind); ok {
+		return v.(*int64)
+	}
+	v, _ := inFlightCounters.LoadOrStore(kind, new(int64))
+	return v.(*int64)
+}
+
+// InFlightAdd 开始处理请求/消息, 处理完成后必须调用 InFlightDone
+func InFlightAdd(kind string) {
+	atomic.AddInt64(inFlightCounter(kind), 1)
+}
+
+// InFlightDone 请求/消息处理完成
+func InFlightDone(kind string) {
+	atomic.AddInt64(inFlightCounter(kind), -1)
+}
+
+// GetInFlight 返回各类型处理中的请求/消息数量
+func GetInFlight() map[string]int64 {
+	m := make(map[string]int64)
+	inFlightCounters.Range(func(key, value interface{}) bool {
+		m[key.(string)] = atomic.LoadInt64(value.(*int64))
+		return true
+	})
+	return m
+}
+
+// WaitInFlightZero 等待所有处理中的请求/消息完成, 超时返回 ctx.Err()
+func WaitInFlightZero(ctx context.Context) error {
+	ticker := time.NewTicker(inFlightWaitPollInterval)
+	defer ticker.Stop()
+	for {
+		busy := false
+		inFlightCounters.Range(func(_, value interface{}) bool {
+			busy = atomic.LoadInt64(value.(*int64)) > 0
+			return !busy
+		})
+		if !busy {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
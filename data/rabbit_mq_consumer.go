@@ -2,6 +2,7 @@ package data
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NeilXu2017/landau/data/uamqp"
@@ -26,6 +27,7 @@ type (
 		deliveryAutoAck      bool
 		deliveryAutoAckValue bool
 		deliveryAsync        bool
+		stopped              int32
 	}
 	// RabbitMQConsumerOptionFunc 参数设置
 	RabbitMQConsumerOptionFunc func(*RabbitMQConsumer) error
@@ -40,6 +42,11 @@ const (
 	DefaultRabbitQosPrefetchCount = 15
 )
 
+var (
+	listeningConsumers     = make(map[*RabbitMQConsumer]struct{}) //StartListen 中的 consumer
+	syncListeningConsumers = sync.Mutex{}
+)
+
 // NewRabbitConsumer RabbitMQConsumer
 func NewRabbitConsumer(options ...RabbitMQConsumerOptionFunc) (*RabbitMQConsumer, error) {
 	c := &RabbitMQConsumer{
@@ -65,15 +72,22 @@ func NewRabbitConsumer(options ...RabbitMQConsumerOptionFunc) (*RabbitMQConsumer
 	return c, nil
 }
 
-// StartListen 侦听消息
+// StartListen 侦听消息, Stop 后返回
 func (c *RabbitMQConsumer) StartListen(callback RabbitMQConsumerCallbackFunc, waitGroup *sync.WaitGroup) {
+	syncListeningConsumers.Lock()
+	listeningConsumers[c] = struct{}{}
+	syncListeningConsumers.Unlock()
 	defer func() {
+		syncListeningConsumers.Lock()
+		delete(listeningConsumers, c)
+		syncListeningConsumers.Unlock()
 		if waitGroup != nil {
 			waitGroup.Done()
 		}
 	}()
 	handle := func(delivery *amqp.Delivery) {
 		message := string(delivery.Body)
+		defer InFlightDone(InFlightMQ)
 		defer func() {
 			if err := recover(); err != nil {
 				log.Error2(c.logger, "[RabbitMQConsumer] Panic:%v Message:%s", err, message)
@@ -85,24 +99,58 @@ func (c *RabbitMQConsumer) StartListen(callback RabbitMQConsumerCallbackFunc, wa
 		log.Info2(c.logger, "[RabbitMQConsumer] [%s] receive message:%s", c.routingKey, message)
 		callback(&message, delivery)
 	}
-	for {
+	for !c.isStopped() {
 		receiver, err := c.consumer.CreateReceiver()
 		if err != nil {
 			log.Error2(c.logger, "[RabbitMQConsumer] CreateReceiver error:%v", err)
 			continue
 		}
+		if c.isStopped() { //CreateReceiver 期间调用了 Stop
+			_ = c.consumer.Cancel()
+		}
 		log.Info2(c.logger, "[RabbitMQConsumer] CreateReceiver success, ExchangeName:%s QueueName:%s Consumer routingKey:%s,wait delivery message now...", c.exOptions.Name, c.queueOptions.Name, c.routingKey)
 		for delivery := range receiver {
+			InFlightAdd(InFlightMQ)
 			if c.deliveryAsync {
 				go handle(&delivery)
 			} else {
 				handle(&delivery)
 			}
 		}
+		if c.isStopped() {
+			log.Info2(c.logger, "[RabbitMQConsumer] [%s] stopped", c.routingKey)
+			return
+		}
 		time.Sleep(c.sleepTime)
 	}
 }
 
+// Stop 停止接收新消息(basic.cancel), 处理中的消息仍可 Ack, StartListen 随后返回
+func (c *RabbitMQConsumer) Stop() {
+	atomic.StoreInt32(&c.stopped, 1)
+	if err := c.consumer.Cancel(); err != nil {
+		log.Error2(c.logger, "[RabbitMQConsumer] [%s] Cancel error:%v", c.routingKey, err)
+	}
+}
+
+func (c *RabbitMQConsumer) isStopped() bool {
+	return atomic.LoadInt32(&c.stopped) == 1
+}
+
+// StopRabbitMQConsumers 停止所有 StartListen 中的 consumer 接收新消息, 优雅退出时在等待处理中的 MQ 消息之前调用
+func StopRabbitMQConsumers() {
+	syncListeningConsumers.Lock()
+	defer syncListeningConsumers.Unlock()
+	for c := range listeningConsumers {
+		c.Stop()
+	}
+}
+
+// IsConnected MQ 连接是否可用
+func (c *RabbitMQConsumer) IsConnected() bool {
+	return c.consumer != nil && c.consumer.Exchange.IsConnected()
+}
+
 // SetRabbitMQConsumerDeliveryAutoAck 设置 RabbitConsumer Delivery Ack
 func SetRabbitMQConsumerDeliveryAutoAck(deliveryAutoAck bool) RabbitMQConsumerOptionFunc {
 	return func(c *RabbitMQConsumer) error {
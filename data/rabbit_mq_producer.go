@@ -1,6 +1,8 @@
 package data
 
 import (
+	"context"
+
 	"github.com/NeilXu2017/landau/data/uamqp"
 	"github.com/NeilXu2017/landau/log"
 	"github.com/streadway/amqp"
@@ -52,7 +54,7 @@ func NewRabbitProducer(options ...RabbitMQProducerOptionFunc) (*RabbitMQProducer
 	return c, nil
 }
 
-// Publish 产生消息
+// Publish 产生消息; 不设置消息头 X-Request-Id, 需要传递 request id 时应使用 PublishContext(c, ...)
 func (c *RabbitMQProducer) Publish(message string, mandatory, immediate bool, options ...RabbitMQProducerPublishOptionFunc) error {
 	publishing := amqp.Publishing{
 		Headers:         amqp.Table{},
@@ -74,6 +76,16 @@ func (c *RabbitMQProducer) Publish(message string, mandatory, immediate bool, op
 	return err
 }
 
+// PublishContext 产生消息, 消息头 X-Request-Id 设置为 ctx 携带的 request id
+func (c *RabbitMQProducer) PublishContext(ctx context.Context, message string, mandatory, immediate bool, options ...RabbitMQProducerPublishOptionFunc) error {
+	return c.Publish(message, mandatory, immediate, append(options, SetRabbitMQProducerPublishContext(ctx))...)
+}
+
+// IsConnected MQ 连接是否可用
+func (c *RabbitMQProducer) IsConnected() bool {
+	return c.producer != nil && c.producer.Exchange.IsConnected()
+}
+
 // SetRabbitMQProducerPublishContentType Publish content type
 func SetRabbitMQProducerPublishContentType(contentType string) RabbitMQProducerPublishOptionFunc {
 	return func(c *amqp.Publishing) error {
@@ -114,6 +126,20 @@ func SetRabbitMQProducerPublishHeaders(headers amqp.Table) RabbitMQProducerPubli
 	}
 }
 
+// SetRabbitMQProducerPublishContext Publish 消息头 X-Request-Id 设置为 ctx 携带的 request id, 可以直接使用 *gin.Context;
+// 需在 SetRabbitMQProducerPublishHeaders 之后设置, 或者使用 PublishContext
+func SetRabbitMQProducerPublishContext(ctx context.Context) RabbitMQProducerPublishOptionFunc {
+	return func(c *amqp.Publishing) error {
+		if requestID := RequestIDFromContext(ctx); requestID != "" {
+			if c.Headers == nil {
+				c.Headers = amqp.Table{}
+			}
+			c.Headers[RequestIDHeader] = requestID
+		}
+		return nil
+	}
+}
+
 // SetRabbitMQProducerLogger 设置 RabbitProducer logger
 func SetRabbitMQProducerLogger(logger string) RabbitMQProducerOptionFunc {
 	return func(c *RabbitMQProducer) error {
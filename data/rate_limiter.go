@@ -0,0 +1,219 @@
+package data
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/log"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+)
+
+type (
+	//RateLimitAlgorithm 限流算法
+	RateLimitAlgorithm int
+	//RateLimiter 限流计数后端
+	RateLimiter interface {
+		//Allow 按 algorithm 计数, window 时间内允许 limit 次请求, burst 为令牌桶容量
+		Allow(key string, algorithm RateLimitAlgorithm, limit int, burst int, window time.Duration) (bool, error)
+		//Acquire 获取并发配额, 成功后需以返回的 token 调用 Release 释放, ttl 为配额最长占用时间(进程异常退出时自动回收)
+		Acquire(key string, limit int, ttl time.Duration) (string, bool, error)
+		//Release 释放 Acquire 获取的并发配额
+		Release(key string, token string) error
+	}
+	_MemoryRateLimiter struct {
+		sync.Mutex
+		buckets     map[string]*_RateLimitBucket
+		concurrency map[string]int
+		lastSweep   time.Time
+	}
+	_RateLimitBucket struct {
+		tokens      float64   //令牌桶: 剩余令牌
+		windowStart time.Time //滑动窗口: 当前窗口起始时间
+		current     int       //滑动窗口: 当前窗口计数
+		previous    int       //滑动窗口: 上一窗口计数
+		last        time.Time //最后访问时间
+		window      time.Duration
+	}
+	_RedisRateLimiter struct {
+		*_RedisSharedClient
+	}
+)
+
+const (
+	//RateLimitTokenBucket 令牌桶, 每 window 生成 limit 个令牌, 容量 burst
+	RateLimitTokenBucket RateLimitAlgorithm = iota
+	//RateLimitSlidingWindow 滑动窗口, 任意 window 时间内不超过 limit 次
+	RateLimitSlidingWindow
+)
+
+var (
+	rateLimitSweepPeriod = time.Minute
+	//令牌桶 KEYS[1] ARGV: rate(每毫秒令牌数) burst now(毫秒) ttl(毫秒)
+	redisTokenBucketScript = redis.NewScript(`
+local v = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local rate, burst, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local tokens, ts = tonumber(v[1]), tonumber(v[2])
+if tokens == nil then tokens, ts = burst, now end
+if now > ts then tokens = math.min(burst, tokens + (now - ts) * rate) ts = now end
+local allowed = 0
+if tokens >= 1 then tokens = tokens - 1 allowed = 1 end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(ts))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return allowed`)
+	//滑动窗口 KEYS[1] 当前窗口 KEYS[2] 上一窗口 ARGV: limit 上一窗口权重(千分比) ttl(毫秒)
+	redisSlidingWindowScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+if previous * tonumber(ARGV[2]) / 1000 + current >= tonumber(ARGV[1]) then return 0 end
+redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1`)
+	//并发配额 ZSET KEYS[1] 成员为每次获取的 token, score 为到期时间; ARGV: limit now(毫秒) token ttl(毫秒)
+	//先删除已到期(未释放)的 token, 进程异常退出未释放的配额在 ttl 后回收
+	redisAcquireScript = redis.NewScript(`
+local now, ttl = tonumber(ARGV[2]), tonumber(ARGV[4])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[1]) then return 0 end
+redis.call('ZADD', KEYS[1], now + ttl, ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ttl)
+return 1`)
+	//KEYS[1] ARGV: token
+	redisReleaseScript = redis.NewScript(`
+return redis.call('ZREM', KEYS[1], ARGV[1])`)
+)
+
+// NewMemoryRateLimiter 本地内存限流后端, 仅限制本实例
+func NewMemoryRateLimiter() RateLimiter {
+	return &_MemoryRateLimiter{
+		buckets:     make(map[string]*_RateLimitBucket),
+		concurrency: make(map[string]int),
+		lastSweep:   time.Now(),
+	}
+}
+
+// NewRedisRateLimiter Redis 限流后端, 集群内共享计数; Redis 访问失败时返回 error, 由调用方决定是否放行
+func NewRedisRateLimiter(db *RedisDatabase) RateLimiter {
+	return &_RedisRateLimiter{_RedisSharedClient: newRedisSharedClient(db)}
+}
+
+func (c *_MemoryRateLimiter) Allow(key string, algorithm RateLimitAlgorithm, limit int, burst int, window time.Duration) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now()
+	c.sweep(now)
+	b, ok := c.buckets[key]
+	if !ok {
+		b = &_RateLimitBucket{tokens: float64(burst), windowStart: now, last: now, window: window}
+		c.buckets[key] = b
+	}
+	allowed := false
+	switch algorithm {
+	case RateLimitSlidingWindow:
+		if elapsed := now.Sub(b.windowStart); elapsed >= window {
+			if elapsed >= 2*window {
+				b.previous = 0
+			} else {
+				b.previous = b.current
+			}
+			b.current, b.windowStart = 0, b.windowStart.Add(elapsed/window*window)
+		}
+		weight := 1 - float64(now.Sub(b.windowStart))/float64(window)
+		if float64(b.previous)*weight+float64(b.current) < float64(limit) {
+			b.current++
+			allowed = true
+		}
+	default:
+		rate := float64(limit) / float64(window)
+		b.tokens = math.Min(float64(burst), b.tokens+float64(now.Sub(b.last))*rate)
+		if b.tokens >= 1 {
+			b.tokens--
+			allowed = true
+		}
+	}
+	b.last = now
+	return allowed, nil
+}
+
+func (c *_MemoryRateLimiter) Acquire(key string, limit int, _ time.Duration) (string, bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.concurrency[key] >= limit {
+		return "", false, nil
+	}
+	c.concurrency[key]++
+	return "", true, nil
+}
+
+// Release 本地计数不使用 token
+func (c *_MemoryRateLimiter) Release(key string, _ string) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.concurrency[key] <= 1 {
+		delete(c.concurrency, key)
+	} else {
+		c.concurrency[key]--
+	}
+	return nil
+}
+
+// sweep 定期清理 2 个窗口内未访问的计数
+func (c *_MemoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < rateLimitSweepPeriod {
+		return
+	}
+	c.lastSweep = now
+	for k, b := range c.buckets {
+		if now.Sub(b.last) > 2*b.window {
+			delete(c.buckets, k)
+		}
+	}
+}
+
+func (c *_RedisRateLimiter) run(script *redis.Script, keys []string, args ...interface{}) (bool, error) {
+	client, err := c.getClient()
+	if err != nil {
+		log.Error2(c.db.logger, "[Redis]\t[RateLimiter] Keys:%v Error:%v", keys, err)
+		return false, err
+	}
+	n, err := script.Run(client, keys, args...).Int()
+	if err != nil {
+		log.Error2(c.db.logger, "[Redis]\t[RateLimiter] Keys:%v Error:%v", keys, err)
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (c *_RedisRateLimiter) Allow(key string, algorithm RateLimitAlgorithm, limit int, burst int, window time.Duration) (bool, error) {
+	now, windowMs := time.Now(), window.Milliseconds()
+	if windowMs <= 0 {
+		windowMs = 1
+	}
+	switch algorithm {
+	case RateLimitSlidingWindow:
+		index := now.UnixMilli() / windowMs
+		weight := 1000 - now.UnixMilli()%windowMs*1000/windowMs
+		keys := []string{"{" + key + "}:" + strconv.FormatInt(index, 10), "{" + key + "}:" + strconv.FormatInt(index-1, 10)} //hash tag 保证 cluster 下同一 slot
+		return c.run(redisSlidingWindowScript, keys, limit, weight, 2*windowMs)
+	default:
+		rate := float64(limit) / float64(windowMs)
+		ttl := int64(math.Ceil(float64(burst)/rate)) + windowMs
+		return c.run(redisTokenBucketScript, []string{key}, rate, burst, now.UnixMilli(), ttl)
+	}
+}
+
+func (c *_RedisRateLimiter) Acquire(key string, limit int, ttl time.Duration) (string, bool, error) {
+	token := uuid.NewString()
+	ok, err := c.run(redisAcquireScript, []string{key}, limit, time.Now().UnixMilli(), token, ttl.Milliseconds())
+	if !ok {
+		token = ""
+	}
+	return token, ok, err
+}
+
+func (c *_RedisRateLimiter) Release(key string, token string) error {
+	_, err := c.run(redisReleaseScript, []string{key}, token)
+	return err
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/NeilXu2017/landau/log"
@@ -25,6 +26,12 @@ type (
 		poolSize     int
 	}
 	RedisOptionFunc func(*RedisDatabase)
+	//_RedisSharedClient 复用同一 redis.Client, 用于限流等高频访问场景
+	_RedisSharedClient struct {
+		sync.Mutex
+		db     *RedisDatabase
+		client *redis.Client
+	}
 )
 
 const (
@@ -202,3 +209,22 @@ func (c *RedisDatabase) WaitLock(key string, lockTimeout int, waitTimeout int) (
 	retryCount := int(time.Second/(retryDelay*time.Millisecond)) * waitTimeout
 	return c.Lock(key, lockTimeout, retryCount, int(retryDelay))
 }
+
+func newRedisSharedClient(db *RedisDatabase) *_RedisSharedClient {
+	return &_RedisSharedClient{db: db}
+}
+
+func (c *_RedisSharedClient) getClient() (*redis.Client, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	client, err := c.db.GetRedisClient()
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	c.client = client
+	return client, nil
+}
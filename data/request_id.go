@@ -0,0 +1,57 @@
+package data
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type (
+	_RequestIDKey struct{}
+)
+
+const (
+	RequestIDHeader      = "X-Request-Id"      //HTTP 请求/响应头, MQ 消息头
+	RequestIDMetadataKey = "x-request-id"      //gRPC metadata
+	RequestIDContextKey  = "landau_request_id" //gin.Context key, gin.Context 作为 context.Context 时同样可以获取
+	requestIDMaxLength   = 128
+)
+
+// NewRequestID 生成 request id
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// IsValidRequestID 检查外部传入的 request id: 非空, 长度不超过 128, 仅包含字母数字及 -_.:
+func IsValidRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithRequestID 返回携带 request id 的 context
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, _RequestIDKey{}, requestID)
+}
+
+// RequestIDFromContext 获取 context 携带的 request id, 支持 ContextWithRequestID 生成的 context 及 gin.Context
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if v, ok := ctx.Value(_RequestIDKey{}).(string); ok {
+		return v
+	}
+	if v, ok := ctx.Value(RequestIDContextKey).(string); ok {
+		return v
+	}
+	return ""
+}
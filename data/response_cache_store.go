@@ -0,0 +1,178 @@
+package data
+
+import (
+	"container/list"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NeilXu2017/landau/log"
+	"github.com/go-redis/redis"
+)
+
+type (
+	//ResponseCacheEntry 缓存的应答
+	ResponseCacheEntry struct {
+		Status int             //HTTP 状态码
+		Body   json.RawMessage //应答内容(json)
+		Expire time.Time       //有效期, 之后至存储过期前为旧应答(stale)
+	}
+	//ResponseCacheStore 应答缓存存储
+	ResponseCacheStore interface {
+		//Get 返回缓存的应答, 不存在时返回 nil, nil
+		Get(key string) (*ResponseCacheEntry, error)
+		//Set 保存应答, ttl 后从存储中删除
+		Set(key string, entry *ResponseCacheEntry, ttl time.Duration) error
+		//DeletePrefix 删除 key 以 prefix 开头的应答
+		DeletePrefix(prefix string) error
+	}
+	_MemoryResponseCacheStore struct {
+		sync.Mutex
+		capacity int
+		lru      *list.List //队首为最近使用
+		items    map[string]*list.Element
+	}
+	_MemoryResponseCacheItem struct {
+		key    string
+		entry  *ResponseCacheEntry
+		expire time.Time
+	}
+	_RedisResponseCacheStore struct {
+		*_RedisSharedClient
+	}
+)
+
+const (
+	defaultResponseCacheCapacity = 10000
+	redisResponseCacheScanCount  = 500
+)
+
+// NewMemoryResponseCacheStore 本地内存 LRU 应答缓存, 超过 capacity(<=0 时为 10000)条时淘汰最久未使用的应答
+func NewMemoryResponseCacheStore(capacity int) ResponseCacheStore {
+	if capacity <= 0 {
+		capacity = defaultResponseCacheCapacity
+	}
+	return &_MemoryResponseCacheStore{capacity: capacity, lru: list.New(), items: make(map[string]*list.Element)}
+}
+
+// NewRedisResponseCacheStore Redis 应答缓存, 集群内共享
+func NewRedisResponseCacheStore(db *RedisDatabase) ResponseCacheStore {
+	return &_RedisResponseCacheStore{_RedisSharedClient: newRedisSharedClient(db)}
+}
+
+func (c *_MemoryResponseCacheStore) Get(key string) (*ResponseCacheEntry, error) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, nil
+	}
+	item := e.Value.(*_MemoryResponseCacheItem)
+	if time.Now().After(item.expire) {
+		c.lru.Remove(e)
+		delete(c.items, key)
+		return nil, nil
+	}
+	c.lru.MoveToFront(e)
+	return item.entry, nil
+}
+
+func (c *_MemoryResponseCacheStore) Set(key string, entry *ResponseCacheEntry, ttl time.Duration) error {
+	c.Lock()
+	defer c.Unlock()
+	item := &_MemoryResponseCacheItem{key: key, entry: entry, expire: time.Now().Add(ttl)}
+	if e, ok := c.items[key]; ok {
+		e.Value = item
+		c.lru.MoveToFront(e)
+		return nil
+	}
+	c.items[key] = c.lru.PushFront(item)
+	for c.lru.Len() > c.capacity {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.items, e.Value.(*_MemoryResponseCacheItem).key)
+	}
+	return nil
+}
+
+func (c *_MemoryResponseCacheStore) DeletePrefix(prefix string) error {
+	c.Lock()
+	defer c.Unlock()
+	for key, e := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.lru.Remove(e)
+			delete(c.items, key)
+		}
+	}
+	return nil
+}
+
+func (c *_RedisResponseCacheStore) Get(key string) (*ResponseCacheEntry, error) {
+	client, err := c.getClient()
+	if err != nil {
+		return nil, err
+	}
+	v, err := client.Get(key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := &ResponseCacheEntry{}
+	if err = json.Unmarshal(v, entry); err != nil {
+		log.Error2(c.db.logger, "[Redis]\t[ResponseCache] Key:%s Error:%v", key, err)
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (c *_RedisResponseCacheStore) Set(key string, entry *ResponseCacheEntry, ttl time.Duration) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return client.Set(key, v, ttl).Err()
+}
+
+// DeletePrefix 使用 SCAN 查找 key, 大量 key 时耗时较长
+func (c *_RedisResponseCacheStore) DeletePrefix(prefix string) error {
+	client, err := c.getClient()
+	if err != nil {
+		return err
+	}
+	match := redisGlobEscape(prefix) + "*"
+	var cursor uint64
+	for {
+		var keys []string
+		if keys, cursor, err = client.Scan(cursor, match, redisResponseCacheScanCount).Result(); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err = client.Del(keys...).Err(); err != nil {
+				return err
+			}
+		}
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// redisGlobEscape 转义 SCAN MATCH 模式中的特殊字符
+func redisGlobEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
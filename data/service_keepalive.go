@@ -2,11 +2,13 @@ package data
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"github.com/NeilXu2017/landau/log"
 	"github.com/gin-gonic/gin"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -70,6 +72,33 @@ type (
 		ReceiveTime string
 	}
 	_SortKeepalivedServiceTraceInfo []_KeepalivedServiceTraceInfo
+	//KeepaliveMesh keepalive 状态, OutputKeepaliveStatics 的 JSON 形式
+	KeepaliveMesh struct {
+		QueryTime               string
+		ServiceName             string
+		ServiceAddress          string
+		SecondaryServiceAddress string
+		HealthCheckPeriod       int
+		HealthCheckTimeout      int
+		ReceiverKeepTimer       int64
+		Node                    []KeepaliveServiceStatus //健康检查的服务
+		TraceCallerService      []KeepaliveServiceStatus //收到健康检查的服务
+		LastTraceService        map[string]string        //key: service name value: 最近请求的 service address
+	}
+	//KeepaliveServiceStatus 服务各地址状态
+	KeepaliveServiceStatus struct {
+		ServiceName string
+		Address     []KeepaliveAddressStatus
+	}
+	//KeepaliveAddressStatus 服务地址状态
+	KeepaliveAddressStatus struct {
+		Address          string
+		SecondaryAddress string //对端 secondary 地址
+		Health           int    //1 ok, 0 check failure
+		OnSecondary      bool   //是否通过 secondary 地址检查通过
+		CallCount        uint64
+		ReceiveTime      string
+	}
 )
 
 var (
@@ -113,12 +142,21 @@ func (a _SortKeepalivedServiceTraceInfo) Less(i, j int) bool {
 }
 
 func MonitorServiceHealthConfigs() {
+	MonitorServiceHealthConfigsContext(context.Background())
+}
+
+// MonitorServiceHealthConfigsContext 周期检查服务地址配置变化, ctx 取消后退出
+func MonitorServiceHealthConfigsContext(ctx context.Context) {
 	if MonitorServiceAddrChange != nil || MonitorServiceAddrChange2 != nil {
 		timer := time.NewTicker(time.Duration(MonitorServiceAddrPeriod) * time.Second)
 		defer timer.Stop()
 		for {
-			<-timer.C
-			_checkServiceHealthConfig()
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				_checkServiceHealthConfig()
+			}
 		}
 	}
 }
@@ -169,13 +207,22 @@ func _checkServiceHealthConfig() {
 }
 
 func StartHealthChecking() {
+	StartHealthCheckingContext(context.Background())
+}
+
+// StartHealthCheckingContext 周期检查服务健康状态, ctx 取消后退出
+func StartHealthCheckingContext(ctx context.Context) {
 	timer := time.NewTicker(time.Duration(HealthCheckPeriod) * time.Second)
 	defer timer.Stop()
 	for {
 		if len(serviceHealthMesh) > 0 {
 			_healthChecking(false)
 		}
-		<-timer.C
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
 	}
 }
 
@@ -478,6 +525,62 @@ func OutputKeepaliveStatics(c *gin.Context) {
 	c.Data(200, "text/html", []byte("nothing"))
 }
 
+// GetKeepaliveMesh 返回 keepalive 状态
+func GetKeepaliveMesh() KeepaliveMesh {
+	m := KeepaliveMesh{
+		QueryTime:               time.Now().Format("2006-01-02 15:04:05"),
+		ServiceName:             ServiceName,
+		ServiceAddress:          ServiceAddress,
+		SecondaryServiceAddress: SecondaryServiceAddress,
+		HealthCheckPeriod:       HealthCheckPeriod,
+		HealthCheckTimeout:      HealthCheckTimeout,
+		ReceiverKeepTimer:       ReceiverKeepTimer,
+		LastTraceService:        make(map[string]string),
+	}
+	serviceStatus := func(name string, d *ServiceHealthInfo) KeepaliveServiceStatus {
+		v := KeepaliveServiceStatus{ServiceName: name}
+		for _, address := range d.Address {
+			_, onSecondary := d.HealthOnSecondary[address]
+			a := KeepaliveAddressStatus{
+				Address:     address,
+				Health:      d.Health[address],
+				OnSecondary: onSecondary,
+				CallCount:   d.CallCount[address],
+				ReceiveTime: time.Unix(d.ReceiveTime[address], 0).Format("2006-01-02 15:04:05"),
+			}
+			syncMeshPrimary.RLock()
+			a.SecondaryAddress = ServiceMeshPrimary2Secondary[address]
+			syncMeshPrimary.RUnlock()
+			v.Address = append(v.Address, a)
+		}
+		return v
+	}
+	syncServiceMesh.RLock()
+	for name, d := range serviceHealthMesh {
+		m.Node = append(m.Node, serviceStatus(name, d))
+	}
+	syncServiceMesh.RUnlock()
+	syncReceiverService.RLock()
+	for name, d := range receiveServiceMesh {
+		m.TraceCallerService = append(m.TraceCallerService, serviceStatus(name, d))
+	}
+	syncReceiverService.RUnlock()
+	LastTraceServiceAddress.Range(func(key, value interface{}) bool {
+		m.LastTraceService[key.(string)] = value.(string)
+		return true
+	})
+	sort.Slice(m.Node, func(i, j int) bool { return m.Node[i].ServiceName < m.Node[j].ServiceName })
+	sort.Slice(m.TraceCallerService, func(i, j int) bool {
+		return m.TraceCallerService[i].ServiceName < m.TraceCallerService[j].ServiceName
+	})
+	return m
+}
+
+// OutputKeepaliveJSON 以 JSON 格式输出 keepalive 状态
+func OutputKeepaliveJSON(c *gin.Context) {
+	c.JSON(http.StatusOK, GetKeepaliveMesh())
+}
+
 func GetServiceAddrByName(serviceName string) (string, bool) {
 	addr, usingSequence, isPrimary := "", 0, true //service address
 	syncServiceMesh.RLock()
@@ -569,7 +672,7 @@ func GetServiceAddrByName(serviceName string) (string, bool) {
 			addr = v.(string)
 		}
 	}
-	if addr != "" && !strings.Contains(addr, "http://") {
+	if addr != "" && !strings.Contains(addr, "http://") && !strings.HasPrefix(addr, GRPCAddressScheme) {
 		addr = fmt.Sprintf("http://%s", addr)
 	}
 	return addr, isPrimary
@@ -624,6 +727,12 @@ func _healthChecking(notifyShutdown bool) {
 	wg, checkTime := sync.WaitGroup{}, time.Now().Unix()
 	check := func(addr, name string) {
 		defer wg.Done()
+		if strings.HasPrefix(addr, GRPCAddressScheme) { //gRPC 服务使用 grpc_health_v1 检查, 不接收 shutdown 通知
+			if !notifyShutdown {
+				_updateServerHealthStatus(name, addr, grpcHealthChecking(addr))
+			}
+			return
+		}
 		req := _HealthCheckRequest{Action: "ServiceHealthCheck", Service: name, CheckTime: checkTime, Checker: ServiceName, CheckerAddress: ServiceAddress, NotifyShutdown: notifyShutdown, PrimaryAddress: ServiceAddress, SecondaryAddress: SecondaryServiceAddress}
 		rsp, healthStatus := &_HealthCheckResponse{}, 0
 		httpHelper, _ := NewHTTPHelper(SetHTTPUrl(fmt.Sprintf("%s/ServiceHealthCheck", addr)), SetHTTPTimeout(HealthCheckTimeout),
@@ -1,11 +1,16 @@
 package uamqp
 
 import (
+	"fmt"
+	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/streadway/amqp"
 )
 
+var consumerTagSeq uint64
+
 func NewConsumer(exchange *Exchange, routingKey string, consumerOptions ConsumerOptions, queueOptions QueueOptions) *Consumer {
 	return &Consumer{
 		Exchange:     exchange,
@@ -74,6 +79,9 @@ func (c *Consumer) CreateReceiver() (<-chan amqp.Delivery, error) {
 		}
 	}
 
+	if c.options.ConsumerTag == "" { //Cancel 需要指定 consumer tag
+		c.options.ConsumerTag = fmt.Sprintf("ctag-%d-%d", os.Getpid(), atomic.AddUint64(&consumerTagSeq, 1))
+	}
 	receiver, err := c.Channel.Consume(
 		c.queueOptions.Name,
 		c.options.ConsumerTag,
@@ -91,6 +99,17 @@ func (c *Consumer) CreateReceiver() (<-chan amqp.Delivery, error) {
 	return receiver, nil
 }
 
+// Cancel 停止接收消息(basic.cancel), 之后 receiver 通道关闭, 已接收的消息仍可 Ack
+func (c *Consumer) Cancel() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.Channel == nil || c.options.ConsumerTag == "" {
+		return nil
+	}
+	return c.Channel.Cancel(c.options.ConsumerTag, false)
+}
+
 // 为当前Consumer创建一个Channel
 func (c *Consumer) getChannel() (*amqp.Channel, error) {
 	c.m.Lock()
@@ -90,6 +90,13 @@ func (ex *Exchange) refreshConn() error {
 	return errors.New("not found alive node")
 }
 
+// IsConnected 连接是否可用
+func (ex *Exchange) IsConnected() bool {
+	ex.m.Lock()
+	defer ex.m.Unlock()
+	return ex.conn != nil && !ex.conn.IsClosed()
+}
+
 // NewChannel 创建新的Channel
 func (ex *Exchange) NewChannel() (*amqp.Channel, error) {
 	if ex.conn == nil {
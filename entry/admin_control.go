@@ -0,0 +1,271 @@
+package entry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	sysLog "log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/NeilXu2017/landau/api"
+	"github.com/NeilXu2017/landau/log"
+	"github.com/NeilXu2017/landau/version"
+)
+
+type (
+	//AdminStatus admin socket status 命令返回的运行状态
+	AdminStatus struct {
+		Pid          int    `json:"pid"`
+		StartTime    string `json:"start_time"`
+		Ready        bool   `json:"ready"`
+		ShuttingDown bool   `json:"shutting_down"`
+		Version      string `json:"version"`
+		BuildTime    string `json:"build_time"`
+	}
+	_AdminControl struct {
+		socketPath string
+		pidFile    string
+		listener   net.Listener
+		stop       context.CancelFunc
+		startTime  time.Time
+		wg         sync.WaitGroup
+	}
+)
+
+const (
+	adminCommandReload = "reload"
+	adminCommandStatus = "status"
+	adminCommandStop   = "stop"
+	adminResponseOK    = "OK"
+	adminResponseError = "ERR"
+	adminTimeout       = 5 * time.Second
+)
+
+var (
+	reload      = flag.Bool("reload", false, "Send reload command to the running instance through admin socket")
+	status      = flag.Bool("status", false, "Query status of the running instance through admin socket")
+	stop        = flag.Bool("stop", false, "Send stop command to the running instance through admin socket")
+	adminSocket = flag.String("admin_socket", "", "Admin unix socket path, overrides LandauServer.AdminSocket")
+	pidFile     = flag.String("pidfile", "", "Pid file path, overrides LandauServer.PidFile")
+)
+
+// appName 程序文件名(不含路径)
+func appName() string {
+	return filepath.Base(os.Args[0])
+}
+
+// adminInstanceName 默认 pidfile 及 admin socket 的文件名: <程序名>-<端口>, 同一主机的多个实例互不冲突
+func (c *LandauServer) adminInstanceName() string {
+	port := c.HTTPServicePort
+	if port <= 0 {
+		port = c.GRPCServicePort
+	}
+	if port > 0 {
+		return fmt.Sprintf("%s-%d", appName(), port)
+	}
+	return appName()
+}
+
+// adminSocketPath 优先使用命令行参数 -admin_socket, 其次 AdminSocket, 默认为临时目录下 <程序名>-<端口>.sock; 返回是否为显式指定
+func (c *LandauServer) adminSocketPath() (string, bool) {
+	switch {
+	case *adminSocket != "":
+		return *adminSocket, true
+	case c.AdminSocket != "":
+		return c.AdminSocket, true
+	}
+	return filepath.Join(os.TempDir(), c.adminInstanceName()+".sock"), false
+}
+
+// pidFilePath 优先使用命令行参数 -pidfile, 其次 PidFile, 默认为临时目录下 <程序名>-<端口>.pid; 返回是否为显式指定
+func (c *LandauServer) pidFilePath() (string, bool) {
+	switch {
+	case *pidFile != "":
+		return *pidFile, true
+	case c.PidFile != "":
+		return c.PidFile, true
+	}
+	return filepath.Join(os.TempDir(), c.adminInstanceName()+".pid"), false
+}
+
+// runAdminCommand 处理 -reload/-status/-stop 命令: 通过 admin socket 发送命令给运行中的实例, 输出结果后退出
+func (c *LandauServer) runAdminCommand() {
+	command := ""
+	switch {
+	case *reload:
+		command = adminCommandReload
+	case *status:
+		command = adminCommandStatus
+	case *stop:
+		command = adminCommandStop
+	default:
+		return
+	}
+	socketPath, _ := c.adminSocketPath()
+	rsp, err := SendAdminCommand(socketPath, command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s error: %v\n", command, err)
+		os.Exit(1)
+	}
+	fmt.Println(rsp)
+	os.Exit(0)
+}
+
+// SendAdminCommand 通过 admin socket 发送命令(reload/status/stop), 返回执行结果, 对端返回失败时返回 error
+func SendAdminCommand(socketPath string, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", socketPath, adminTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(adminTimeout))
+	if _, err = fmt.Fprintf(conn, "%s\n", command); err != nil {
+		return "", err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	switch {
+	case line == adminResponseOK:
+		return "", nil
+	case strings.HasPrefix(line, adminResponseOK+" "):
+		return strings.TrimPrefix(line, adminResponseOK+" "), nil
+	case strings.HasPrefix(line, adminResponseError):
+		return "", errors.New(strings.TrimSpace(strings.TrimPrefix(line, adminResponseError)))
+	}
+	return "", fmt.Errorf("invalid response: %s", line)
+}
+
+// startAdminControl 写入 pidfile 并监听 admin socket, stop 命令调用 stopFunc; 返回关闭函数.
+// socket 或 pidfile 已被其他运行中的实例占用时: 显式指定的路径启动失败, 默认路径记录错误日志并跳过
+func (c *LandauServer) startAdminControl(stopFunc context.CancelFunc) func() {
+	if c.DisableAdminControl {
+		return func() {}
+	}
+	socketPath, explicitSocket := c.adminSocketPath()
+	pidFilePath, explicitPidFile := c.pidFilePath()
+	a := &_AdminControl{socketPath: socketPath, pidFile: pidFilePath, stop: stopFunc, startTime: time.Now()}
+	if err := a.start(); err != nil {
+		if explicitSocket || explicitPidFile {
+			sysLog.Fatalf("[AdminControl] start error: %v", err)
+		}
+		log.Error("[AdminControl] start error: %v", err)
+		return func() {}
+	}
+	log.Info("[AdminControl] pid file:%s admin socket:%s", a.pidFile, a.socketPath)
+	return a.close
+}
+
+func (a *_AdminControl) start() error {
+	if conn, err := net.DialTimeout("unix", a.socketPath, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("admin socket %s is in use by another instance", a.socketPath)
+	}
+	if pid := readPidFile(a.pidFile); pid > 0 && pid != os.Getpid() && isProcessAlive(pid) {
+		return fmt.Errorf("pid file %s is in use by another instance(pid:%d)", a.pidFile, pid)
+	}
+	_ = os.Remove(a.socketPath) //上次异常退出残留的 socket 文件
+	l, err := net.Listen("unix", a.socketPath)
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(a.pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		_ = l.Close()
+		_ = os.Remove(a.socketPath)
+		return err
+	}
+	a.listener = l
+	a.wg.Add(1)
+	go a.serve()
+	return nil
+}
+
+// readPidFile 读取 pidfile 中的 pid, 文件不存在或内容无效时返回 0
+func readPidFile(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(b)))
+	return pid
+}
+
+// isProcessAlive 进程是否存在(无权限发送信号的进程也视为存在)
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func (a *_AdminControl) serve() {
+	defer a.wg.Done()
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			return
+		}
+		a.handle(conn)
+	}
+}
+
+func (a *_AdminControl) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(adminTimeout))
+	command, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+	command = strings.TrimSpace(command)
+	log.Info("[AdminControl] receive command:%s", command)
+	rsp := a.execute(command)
+	_, _ = fmt.Fprintf(conn, "%s\n", rsp)
+	if command == adminCommandStop && a.stop != nil {
+		a.stop()
+	}
+}
+
+// execute 执行命令, 返回 "OK [message]" 或 "ERR message"
+func (a *_AdminControl) execute(command string) string {
+	switch command {
+	case adminCommandReload:
+		if reloadCallback == nil {
+			return adminResponseError + " reload not supported"
+		}
+		reloadCallback()
+		return adminResponseOK + " reloaded"
+	case adminCommandStatus:
+		b, _ := json.Marshal(AdminStatus{
+			Pid:          os.Getpid(),
+			StartTime:    a.startTime.Format("2006-01-02 15:04:05"),
+			Ready:        api.IsServiceReady() && !api.IsServiceShuttingDown(),
+			ShuttingDown: api.IsServiceShuttingDown(),
+			Version:      version.GetReleaseVersion(),
+			BuildTime:    version.GetBuildTime(),
+		})
+		return adminResponseOK + " " + string(b)
+	case adminCommandStop:
+		return adminResponseOK + " stopping"
+	}
+	return fmt.Sprintf("%s unknown command: %s", adminResponseError, command)
+}
+
+// close 停止监听, 删除 socket 文件; pidfile 仍为本进程时删除
+func (a *_AdminControl) close() {
+	_ = a.listener.Close()
+	a.wg.Wait()
+	_ = os.Remove(a.socketPath)
+	if readPidFile(a.pidFile) == os.Getpid() {
+		_ = os.Remove(a.pidFile)
+	}
+}
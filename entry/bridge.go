@@ -1,6 +1,8 @@
 package entry
 
 import (
+	"net/http"
+
 	"github.com/NeilXu2017/landau/api"
 	"github.com/NeilXu2017/landau/util"
 	"github.com/gin-gonic/gin"
@@ -30,18 +32,25 @@ type (
 		HTTPCustomLog                     api.HTTPCustomLogTag                            //HTTP服务日志自定义Tag生成
 		grpcServer                        *grpc.Server                                    //GRPC 服务引擎，内部生成维护
 		ginRouter                         *gin.Engine                                     //HTTP服务引擎，内部生成维护
+		httpServer                        *http.Server                                    //HTTP服务，内部生成维护
+		secondHttpServer                  *http.Server                                    //secondary address HTTP服务，内部生成维护
+		adminHttpServer                   *http.Server                                    //管理接口独立端口HTTP服务，内部生成维护
+		components                        []Component                                     //注册的组件, 由 RegisterComponent 添加
+		startedComponents                 []Component                                     //已启动的组件, 按启动顺序，内部生成维护
+		httpMux                           *_MuxHandler                                    //单端口 HTTP/gRPC 分发，内部生成维护
 		HTTPAuditLog                      api.HTTPAuditLog                                //审核日志记录
 		PostBindingComplex                [2]string                                       //需要支持复杂JSON Unmarshal 的请求，第一个元素URL,第二个元素Action,多个值用逗号分割
 		UnRegisterHTTPHandle              api.HTTPHandleFunc                              //未注册的Action Handle 处理入口
 		DynamicHTTPServiceAddress         func() string                                   //修改HTTP服务的IP地址,使用场景:检测本机内网IP，更换原先配置的 127.0.0.1 地址形式
 		EnablePrometheusMetric            bool                                            //是否启动Prometheus Metric 服务提供监测
-		PrometheusMetricHost              string                                          //Prometheus Metric 服务IP地址, 默认与 HTTPServiceAddress一致
-		PrometheusMetricPort              int                                             //Prometheus Metric 服务端口,默认 HTTPServicePort+3000
+		PrometheusMetricHost              string                                          //Prometheus Metric 服务IP地址, 默认与 HTTPServiceAddress一致(仅 gRPC 服务时为 GRPCServiceAddress)
+		PrometheusMetricPort              int                                             //Prometheus Metric 服务端口,默认 HTTPServicePort+3000(仅 gRPC 服务时为 GRPCServicePort+3000)
 		PrometheusMetricNamespace         string                                          //Prometheus Metric 上报指标 namespace, 默认值为空
 		PrometheusNodeId                  string                                          //Prometheus Metric 上报指标,label node_id 默认值为空
 		DisableGracefulStopping           bool                                            //禁止优雅停止服务,默认值为 false, 启用优雅stopping
 		GracefulTimeout                   uint64                                          //优雅stopping 等待超时时间, 默认值: 60秒
-		DynamicReloadConfig               func()                                          //通过信号机制触发回调用户函数,一般用于重新加载配置, window平台不支持信号. 使用了 SIGUSR1 信号,需要同时 DisableGracefulStopping=false 时生效
+		ShutdownPropagationDelay          uint64                                          //优雅stopping 标记 not ready 并通知 keepalive 对端后, 等待对端切走流量的时间(秒), 之后等待处理中的请求完成再关闭监听, 默认值: 0
+		DynamicReloadConfig               func()                                          //通过信号机制触发回调用户函数,一般用于重新加载配置, window平台不支持信号. 使用了 SIGUSR1 信号或 admin socket reload 命令,需要同时 DisableGracefulStopping=false 时生效
 		DisableServiceHealthReceiver      bool                                            //是否禁用 service health receiver 接口
 		CheckServiceHealth                func() map[string][]string                      //需要进行健康检查的 service
 		CheckServiceHealth2               func() (map[string][]string, map[string]string) //需要进行健康检查的 service,service 有第2个地址
@@ -57,6 +66,30 @@ type (
 		ReceivedServiceCallback           func(string, string) bool                       //收到服务推送地址 回调设置 参数 service name, service url address
 		ExcludeInitServiceDisabled        []string                                        //不受 InitServiceDisabled 影响的请求 action 或者 url
 		DestoryCallback                   func()                                          //stoped 之前调用
+		DisableHealthProbe                bool                                            //是否禁用 liveness/readiness 接口
+		LivenessPath                      string                                          //liveness 接口地址, 默认 /livez
+		ReadinessPath                     string                                          //readiness 接口地址, 默认 /readyz, 服务 Disable 或优雅停止阶段返回 503
+		HTTPTLS                           *TLSConfig                                      //HTTP服务证书配置, 非空时启用 HTTPS
+		SecondaryHTTPTLS                  *TLSConfig                                      //secondary address HTTP服务证书配置, 为空时使用 HTTPTLS
+		GRPCTLS                           *TLSConfig                                      //gRPC服务证书配置, 非空时启用 TLS
+		certReloaders                     []*_CertReloader                                //证书加载器，内部生成维护
+		DisableGRPCInterceptor            bool                                            //是否禁用内置 gRPC 拦截器(日志,Prometheus指标,ACL,ServiceDisabled)
+		GRPCCheckACL                      api.GRPCCheckACL                                //gRPC服务权限检查函数, 为空不检查
+		GRPCUnaryInterceptors             []grpc.UnaryServerInterceptor                   //用户 gRPC unary 拦截器, 在内置拦截器之后执行
+		GRPCStreamInterceptors            []grpc.StreamServerInterceptor                  //用户 gRPC stream 拦截器, 在内置拦截器之后执行
+		DisableGRPCHealthServer           bool                                            //是否禁用内置 grpc_health_v1 服务, RegisterGRPCHandle 已注册时自动跳过
+		GRPCOnHTTPPort                    bool                                            //gRPC 与 HTTP 共用 HTTPServicePort(含 secondary address), 按 content-type application/grpc 分发, 同时支持 h2c; 此时忽略 GRPCServicePort/GRPCTLS
+		PidFile                           string                                          //pid 文件, 默认为临时目录下 <程序名>-<端口>.pid, 命令行 -pidfile 优先; 显式指定且被其他运行中的实例占用时启动失败
+		AdminSocket                       string                                          //admin unix socket, 默认为临时目录下 <程序名>-<端口>.sock(端口为 HTTPServicePort, 未设置时为 GRPCServicePort), 命令行 -admin_socket 优先, 显式指定且被占用时启动失败; -reload/-status/-stop 通过此 socket 控制运行中的实例
+		DisableAdminControl               bool                                            //是否禁用 pid 文件及 admin socket
+		EnableAdminAPI                    bool                                            //是否启用运行时管理 HTTP 接口, 参见 api.RegisterAdminAPI
+		AdminAPIPrefix                    string                                          //管理接口路径前缀, 默认 /admin
+		AdminAPIPort                      int                                             //管理接口独立端口(监听 HTTPServiceAddress), 为 0 时注册在 HTTP 服务的 AdminAPIPrefix 下
+		AdminAPICheckACL                  api.HTTPCheckACL                                //管理接口权限检查函数, 为空时仅允许本机访问; AdminAPIPort 为 0 时必须设置, 否则启动失败
+		OpenAPIPath                       string                                          //OpenAPI 3 文档地址, 为空时不提供, 文档信息参见 api.SetOpenAPIInfo
+		CORSPolicy                        *api.CORSPolicy                                 //全局跨域策略, 为空时沿用回显 Origin 的旧行为, URL/Action 策略参见 api.SetURLCORSPolicy/api.SetActionCORSPolicy
+		HandleTimeout                     int                                             //全局 HTTP 处理超时秒数, 0 不限制, URL/Action 设置参见 api.SetURLHandleTimeout/api.SetActionHandleTimeout
+		BatchActionPath                   string                                          //批量 Action 接口地址, 为空时不提供, 数量及并行限制参见 api.SetBatchActionLimit, 请求体大小参见 api.SetBatchActionMaxBytes
 	}
 )
 
@@ -65,4 +98,10 @@ const (
 	DefaultLogger = "main"
 	//DefaultGinLogger 缺省gin使用的logger
 	DefaultGinLogger = "gin"
+	//DefaultLivenessPath 缺省 liveness 接口地址
+	DefaultLivenessPath = "/livez"
+	//DefaultReadinessPath 缺省 readiness 接口地址
+	DefaultReadinessPath = "/readyz"
+	//DefaultAdminAPIPrefix 缺省管理接口路径前缀
+	DefaultAdminAPIPrefix = "/admin"
 )
@@ -0,0 +1,151 @@
+package entry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/NeilXu2017/landau/log"
+)
+
+type (
+	//Component 应用组件(DB 连接池, MQ 消费者, Redis 订阅, ES bulk 等).
+	//按 DependsOn 拓扑顺序在 HTTP/gRPC/cron 之前启动; 在 HTTP/gRPC/cron 及 DestoryCallback 停止后逆序停止
+	Component struct {
+		Name         string                          //组件名称, 唯一
+		DependsOn    []string                        //依赖的组件名称, 依赖的组件先启动, 后停止
+		Start        func(ctx context.Context) error //启动, 可以为空
+		Stop         func(ctx context.Context) error //停止, 可以为空
+		StartTimeout time.Duration                   //启动超时, 默认 DefaultComponentTimeout
+		StopTimeout  time.Duration                   //停止超时, 默认 DefaultComponentTimeout
+	}
+)
+
+const (
+	//DefaultComponentTimeout 组件缺省启动/停止超时时间
+	DefaultComponentTimeout = 30 * time.Second
+)
+
+// RegisterComponent 注册组件, 需在 Start/Run 之前调用
+func (c *LandauServer) RegisterComponent(components ...Component) {
+	c.components = append(c.components, components...)
+}
+
+// sortComponents 按依赖关系拓扑排序, 无依赖关系的组件保持注册顺序; 名称重复, 依赖不存在或循环依赖返回错误
+func sortComponents(components []Component) ([]Component, error) {
+	index := make(map[string]int, len(components))
+	for i, comp := range components {
+		if _, ok := index[comp.Name]; ok {
+			return nil, fmt.Errorf("[Component] duplicate component %s", comp.Name)
+		}
+		index[comp.Name] = i
+	}
+	inDegree := make([]int, len(components))
+	dependents := make([][]int, len(components))
+	for i, comp := range components {
+		for _, d := range comp.DependsOn {
+			j, ok := index[d]
+			if !ok {
+				return nil, fmt.Errorf("[Component] %s depends on unknown component %s", comp.Name, d)
+			}
+			inDegree[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	sorted := make([]Component, 0, len(components))
+	done := make([]bool, len(components))
+	for len(sorted) < len(components) {
+		next := -1
+		for i := range components {
+			if !done[i] && inDegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			var names []string
+			for i, comp := range components {
+				if !done[i] {
+					names = append(names, comp.Name)
+				}
+			}
+			return nil, fmt.Errorf("[Component] circular dependency among %v", names)
+		}
+		done[next] = true
+		sorted = append(sorted, components[next])
+		for _, j := range dependents[next] {
+			inDegree[j]--
+		}
+	}
+	return sorted, nil
+}
+
+// startComponents 按拓扑顺序启动组件, 任一组件启动失败时逆序停止已启动的组件
+func (c *LandauServer) startComponents(ctx context.Context) error {
+	c.startedComponents = nil
+	sorted, err := sortComponents(c.components)
+	if err != nil {
+		return err
+	}
+	for _, comp := range sorted {
+		if comp.Start != nil {
+			start := time.Now()
+			err := callComponent(ctx, comp.StartTimeout, comp.Start)
+			if err != nil {
+				log.Error("[Component] [%s] [%s] start error: %v", comp.Name, time.Since(start), err)
+				errs := runErrors{fmt.Errorf("[Component:%s] start error: %w", comp.Name, err)}
+				if stopErr := c.stopComponents(); stopErr != nil {
+					errs = append(errs, stopErr)
+				}
+				return errs
+			}
+			log.Info("[Component] [%s] [%s] started.", comp.Name, time.Since(start))
+		}
+		c.startedComponents = append(c.startedComponents, comp)
+	}
+	return nil
+}
+
+// stopComponents 逆序停止已启动的组件, 停止失败不影响其他组件, 返回合并后的错误
+func (c *LandauServer) stopComponents() error {
+	var errs runErrors
+	for i := len(c.startedComponents) - 1; i >= 0; i-- {
+		comp := c.startedComponents[i]
+		if comp.Stop == nil {
+			continue
+		}
+		start := time.Now()
+		if err := callComponent(context.Background(), comp.StopTimeout, comp.Stop); err != nil {
+			log.Error("[Component] [%s] [%s] stop error: %v", comp.Name, time.Since(start), err)
+			errs = append(errs, fmt.Errorf("[Component:%s] stop error: %w", comp.Name, err))
+		} else {
+			log.Info("[Component] [%s] [%s] stopped.", comp.Name, time.Since(start))
+		}
+	}
+	c.startedComponents = nil
+	return errs.errorOrNil()
+}
+
+// callComponent 在超时时间内执行 f, 超时返回 context.DeadlineExceeded(f 仍在后台执行); panic 转为错误
+func callComponent(ctx context.Context, timeout time.Duration, f func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		timeout = DefaultComponentTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if e := recover(); e != nil {
+				result <- fmt.Errorf("panic: %v", e)
+			}
+		}()
+		result <- f(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,6 +2,7 @@ package entry
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,10 +11,7 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
-	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -29,18 +27,38 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
+type (
+	//runErrors 启动/停止过程中收集的错误
+	runErrors []error
+)
+
+const (
+	grpcHealthServiceName = "grpc.health.v1.Health"
+)
+
 var (
-	srv             *http.Server                                        //saved to be used in graceful stopping
-	secondSrv       *http.Server                                        //saved to be used in graceful stopping
-	grpcServer      *grpc.Server                                        //saved to be used in graceful stopping
-	reload          = flag.Bool("reload", false, "Signal reload event") //reload cmd
-	reloadCallback  func()                                              //reload 回调
-	destoryCallback func()                                              //destory callback
+	reloadCallback func() //reload 回调, SIGUSR1 信号或 admin socket reload 命令触发
 )
 
+func (e runErrors) Error() string {
+	msg := make([]string, 0, len(e))
+	for _, err := range e {
+		msg = append(msg, err.Error())
+	}
+	return strings.Join(msg, "; ")
+}
+
+func (e runErrors) errorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
 // Start 服务启动入口,作为 web server 或 grpc server
 func (c *LandauServer) Start() {
 	flag.Parse()
@@ -48,13 +66,28 @@ func (c *LandauServer) Start() {
 		c.ParseArgs()
 	}
 	version.ShowVersion()
-	if !c.DisableGracefulStopping && c.DynamicReloadConfig != nil {
-		reloadCallback = c.DynamicReloadConfig
+	if !c.DisableGracefulStopping && (c.DynamicReloadConfig != nil || c.isTLSEnabled()) {
+		reloadCallback = c.reloadConfig
 	}
-	if c.DestoryCallback != nil {
-		destoryCallback = c.DestoryCallback
+	c.runAdminCommand()
+	ctx, cancel := context.WithCancel(context.Background())
+	if !c.DisableGracefulStopping {
+		ctx, cancel = signalContext()
 	}
-	makeReloadSignal()
+	defer cancel()
+	closeAdminControl := c.startAdminControl(cancel)
+	err := c.Run(ctx)
+	closeAdminControl()
+	if err != nil {
+		sysLog.Fatalf("[Engine] Run error,err:%v", err)
+	}
+	log.Close()
+}
+
+// Run 启动 HTTP/gRPC/cron/keepalive 组件并阻塞, ctx 取消后停止所有组件;
+// 返回启动及停止过程中的错误, 不调用 log.Fatalf, 也不监听系统信号.
+// 任一服务异常退出时, 其余组件随之停止.
+func (c *LandauServer) Run(ctx context.Context) error {
 	log.LoadLogConfig(c.LogConfig, c.DefaultLoggerName)
 	if c.GinLoggerName != "" {
 		gin.DefaultWriter = log.NewConsoleLogger(c.GinLoggerName)
@@ -66,151 +99,312 @@ func (c *LandauServer) Start() {
 	if c.CustomInit != nil {
 		c.CustomInit()
 	}
-	if c.HTTPServicePort > 0 || c.GRPCServicePort > 0 {
-		if c.GetCronTasks != nil {
-			p, jobs := c.GetCronTasks()
-			util.StartCronJob(p, jobs)
-		}
-		if c.GRPCServicePort > 0 {
-			c.grpcServer = grpc.NewServer()
-			grpcServer = c.grpcServer
-			c.RegisterGRPCHandle(c.grpcServer)
-			reflection.Register(c.grpcServer)
-			startGRPC := func() {
-				address := fmt.Sprintf("%s:%d", util.IPConvert(c.GRPCServiceAddress, util.IPV6Bracket), c.GRPCServicePort)
-				log.Info("[gRPC] Listen address:%s", address)
-				if gRPCListen, err := net.Listen("tcp", address); err == nil {
-					if serverErr := c.grpcServer.Serve(gRPCListen); serverErr != nil {
-						sysLog.Fatalf("[gRPC] Start gRPC server error,err:%v", serverErr)
-					}
-				} else {
-					sysLog.Fatalf("[gRPC] listen gRPC address error,err:%v", err)
-				}
-			}
-			if c.HTTPServicePort > 0 {
-				go startGRPC()
-			} else {
-				if c.DisableGracefulStopping {
-					startGRPC()
-				} else {
-					go startGRPC()
-					gracefulStop(c.GracefulTimeout)
-				}
+	if c.HTTPServicePort <= 0 && c.GRPCServicePort <= 0 {
+		return nil
+	}
+	if c.EnableAdminAPI && c.AdminAPIPort <= 0 && c.AdminAPICheckACL == nil && c.HTTPServicePort > 0 {
+		return errors.New("[AdminAPI] AdminAPICheckACL is required when admin API is registered on the HTTP service port")
+	}
+	if err := c.startComponents(ctx); err != nil {
+		return err
+	}
+	api.SetServiceShuttingDown(false)
+	api.SetServiceReady(!c.InitServiceDisabled)
+	for _, d := range c.ExcludeInitServiceDisabled {
+		api.AddExcludeServiceDisabled(d)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if c.GetCronTasks != nil {
+		p, jobs := c.GetCronTasks()
+		util.StartCronJob(p, jobs)
+	}
+	serveErr := make(chan error, 4)
+	serve := func(name string, f func() error) {
+		go func() {
+			if err := f(); err != nil {
+				serveErr <- fmt.Errorf("[%s] serve error: %w", name, err)
 			}
+		}()
+	}
+	c.certReloaders, c.httpMux = nil, nil
+	c.preparePrometheus()
+	if c.GRPCOnHTTPPort && c.HTTPServicePort > 0 {
+		if c.RegisterGRPCHandle != nil { //与 HTTP 共用端口, TLS 由 HTTP 服务处理
+			c.grpcServer = c.newGRPCServer(c.grpcInterceptorOptions())
 		}
-		if c.HTTPServicePort > 0 {
-			c.ginRouter = gin.Default()
-			if c.RegisterHTTPHandles != nil {
-				c.RegisterHTTPHandles()
-			}
-			if c.RegisterHTTPCustomHandles != nil {
-				c.RegisterHTTPCustomHandles(c.ginRouter)
-			}
-			if !c.DisableServiceHealthReceiver {
-				healthReceiverLog := func(response interface{}) string { return fmt.Sprintf("%v", response) }
-				api.AddExcludeServiceDisabled("ServiceHealthCheck")
-				api.AddExcludeServiceDisabled("/ServiceHealthCheck")
-				api.AddExcludeServiceDisabled("/output_keepalived_trace")
-				api.AddHTTPHandle("/ServiceHealthCheck", "ServiceHealthCheck", data.NewServiceHealthCheckRequest, data.DoHealthCheck, healthReceiverLog, "health_receiver")
-				c.ginRouter.GET("/output_keepalived_trace", data.OutputKeepaliveStatics)
-			}
-			api.DisableTraceServiceAddress = c.DisableTraceServiceAddress
-			api.EnableMonitorHttpAPI = c.EnableMonitorAPI
-			api.NotifyHttpAPIWeChatRobot = c.NotifyAPIWeChatRobot
-			data.ServiceName = c.ServiceName
-			api.ServiceDisabled = c.InitServiceDisabled
-			data.ReceivedServiceCallback = c.ReceivedServiceCallback
-			for _, d := range c.ExcludeInitServiceDisabled {
-				api.ExcludeInitServiceDisabled[d] = struct{}{}
-			}
-			api.SetPostBindingComplex(c.PostBindingComplex)
-			api.SetUnRegisterHandle(c.UnRegisterHTTPHandle)
-			api.RegisterHTTPHandle(c.ginRouter)
-			api.RegisterRestfulHTTPHandle(c.ginRouter)
-			api.SetHTTPCheckACL(c.HTTPNeedCheckACL, c.HTTPCheckACL)
-			api.SetHTTPCustomLogTag(c.HTTPEnableCustomLogTag, c.HTTPCustomLog)
-			api.SetHTTPAuditLog(c.HTTPAuditLog)
-			addr := c.HTTPServiceAddress
-			if c.DynamicHTTPServiceAddress != nil {
-				addr = c.DynamicHTTPServiceAddress()
-			}
-			prometheus.SetNamespace(c.PrometheusMetricNamespace)
-			prometheus.SetNodeId(c.PrometheusNodeId)
-			if c.PrometheusMetricHost != "" {
-				prometheus.SetServerHost(c.PrometheusMetricHost)
-			} else {
-				prometheus.SetServerHost(addr)
-			}
-			if c.PrometheusMetricPort > 0 {
-				prometheus.SetServerPort(c.PrometheusMetricPort)
-			} else {
-				prometheus.SetServerPort(c.HTTPServicePort + 3000)
-			}
-			if c.EnablePrometheusMetric {
-				go prometheus.StartApiMetric()
-			}
-			if addr != "" && addr != "0.0.0.0" && addr != "::" {
-				data.LocalPrimaryAddress = addr
-				log.Info("[LocalPrimaryAddress] %s", data.LocalPrimaryAddress)
-			}
-			address := fmt.Sprintf("%s:%d", util.IPConvert(addr, util.IPV6Bracket), c.HTTPServicePort)
-			data.ServiceAddress = address
-			secondaryAddress := ""
-			if c.SecondaryServiceAddress != "" {
-				secondaryAddress = fmt.Sprintf("%s:%d", util.IPConvert(c.SecondaryServiceAddress, util.IPV6Bracket), c.HTTPServicePort)
-				data.SecondaryServiceAddress = secondaryAddress
-				if c.SecondaryServiceAddress != "" && c.SecondaryServiceAddress != "0.0.0.0" && c.SecondaryServiceAddress != "::" {
-					data.LocalSecondaryAddress = c.SecondaryServiceAddress
-					log.Info("[LocalSecondaryAddress] %s", data.LocalSecondaryAddress)
-				}
-			}
-			if c.CheckServiceHealth != nil || c.CheckServiceHealth2 != nil {
-				if c.CheckServiceHealthPeriod > 0 {
-					data.MonitorServiceAddrPeriod = c.CheckServiceHealthPeriod
-				}
-				data.DisableAssignSourceIp = c.DisableCheckServiceHealthSourceIp
-				data.MonitorServiceAddrChange2 = c.CheckServiceHealth2
-				data.MonitorServiceAddrChange = c.CheckServiceHealth
-				data.RegisterServiceHealth()
-				go data.MonitorServiceHealthConfigs()
-				go data.StartHealthChecking()
+	} else if c.GRPCServicePort > 0 {
+		grpcTLS, err := c.newTLSConfig("gRPC", c.GRPCTLS, grpcNextProtos)
+		if err != nil {
+			return c.abortRun(err)
+		}
+		opts := c.grpcInterceptorOptions()
+		if grpcTLS != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(grpcTLS)))
+		}
+		c.grpcServer = c.newGRPCServer(opts)
+		address := fmt.Sprintf("%s:%d", util.IPConvert(c.GRPCServiceAddress, util.IPV6Bracket), c.GRPCServicePort)
+		log.Info("[gRPC] Listen address:%s", address)
+		gRPCListen, err := net.Listen("tcp", address)
+		if err != nil {
+			return c.abortRun(fmt.Errorf("[gRPC] listen gRPC address error: %w", err))
+		}
+		serve("gRPC", func() error { return c.grpcServer.Serve(gRPCListen) })
+	}
+	if c.HTTPServicePort > 0 {
+		httpTLS, err := c.newTLSConfig("HTTP", c.HTTPTLS, httpNextProtos)
+		if err != nil {
+			return c.abortRun(err)
+		}
+		secondHttpTLS := httpTLS
+		if c.SecondaryHTTPTLS != nil {
+			if secondHttpTLS, err = c.newTLSConfig("HTTP-Secondary", c.SecondaryHTTPTLS, httpNextProtos); err != nil {
+				return c.abortRun(err)
 			}
-			log.Info("[HTTP] Listen address:%s", address)
-			if secondaryAddress != "" {
-				log.Info("[HTTP] Listen secondary address:%s", secondaryAddress)
+		}
+		address, secondaryAddress := c.prepareHTTP(runCtx)
+		if c.GRPCOnHTTPPort {
+			c.httpMux = &_MuxHandler{httpHandler: c.ginRouter, grpcServer: c.grpcServer}
+		}
+		log.Info("[HTTP] Listen address:%s", address)
+		if c.httpServer, err = c.newHTTPServer(address, httpTLS); err != nil {
+			return c.abortRun(fmt.Errorf("[HTTP] configure server error: %w", err))
+		}
+		httpListen, err := net.Listen("tcp", address)
+		if err != nil {
+			return c.abortRun(fmt.Errorf("[HTTP] listen address error: %w", err))
+		}
+		serve("HTTP", func() error { return serveHTTP(c.httpServer, httpListen) })
+		if secondaryAddress != "" {
+			log.Info("[HTTP] Listen secondary address:%s", secondaryAddress)
+			if c.secondHttpServer, err = c.newHTTPServer(secondaryAddress, secondHttpTLS); err != nil {
+				return c.abortRun(fmt.Errorf("[HTTP] configure secondary server error: %w", err))
 			}
-			if c.DisableGracefulStopping {
-				if secondaryAddress != "" {
-					secondSrv = &http.Server{Addr: secondaryAddress, Handler: c.ginRouter}
-					go func() {
-						if err := secondSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-							sysLog.Fatalf("[HTTP] Start gin server error,err:%v", err)
-						}
-					}()
-				}
-				if err := c.ginRouter.Run(address); err != nil {
-					sysLog.Fatalf("[HTTP] Start gin server error,err:%v", err)
-				}
-			} else {
-				srv = &http.Server{Addr: address, Handler: c.ginRouter}
-				go func() {
-					if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-						sysLog.Fatalf("[HTTP] Start gin server error,err:%v", err)
-					}
-				}()
-				if secondaryAddress != "" {
-					secondSrv = &http.Server{Addr: secondaryAddress, Handler: c.ginRouter}
-					go func() {
-						if err := secondSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-							sysLog.Fatalf("[HTTP] Start gin server error,err:%v", err)
-						}
-					}()
-				}
-				gracefulStop(c.GracefulTimeout)
+			secondListen, err := net.Listen("tcp", secondaryAddress)
+			if err != nil {
+				return c.abortRun(fmt.Errorf("[HTTP] listen secondary address error: %w", err))
 			}
+			serve("HTTP-Secondary", func() error { return serveHTTP(c.secondHttpServer, secondListen) })
 		}
 	}
-	log.Close()
+	if c.EnableAdminAPI && c.AdminAPIPort > 0 {
+		adminTLS, err := c.newTLSConfig("HTTP-Admin", c.HTTPTLS, httpNextProtos)
+		if err != nil {
+			return c.abortRun(err)
+		}
+		adminRouter := gin.New()
+		adminRouter.Use(gin.Recovery())
+		api.RegisterAdminAPI(adminRouter.Group(c.adminAPIPrefix()), c.AdminAPICheckACL)
+		address := fmt.Sprintf("%s:%d", util.IPConvert(c.HTTPServiceAddress, util.IPV6Bracket), c.AdminAPIPort)
+		log.Info("[HTTP-Admin] Listen address:%s", address)
+		adminListen, err := net.Listen("tcp", address)
+		if err != nil {
+			return c.abortRun(fmt.Errorf("[HTTP-Admin] listen address error: %w", err))
+		}
+		c.adminHttpServer = &http.Server{Addr: address, Handler: adminRouter, TLSConfig: adminTLS}
+		serve("HTTP-Admin", func() error { return serveHTTP(c.adminHttpServer, adminListen) })
+	}
+	var errs runErrors
+	select {
+	case <-runCtx.Done():
+		log.Info("[Engine] context done, Shutdown Server ...")
+	case err := <-serveErr:
+		log.Error("[Engine] %v, Shutdown Server ...", err)
+		errs = append(errs, err)
+	}
+	cancel()
+	if err := c.shutdown(c.GracefulTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	log.Info("[Engine] Shutdown Server completed.")
+	return errs.errorOrNil()
+}
+
+// preparePrometheus 设置 Prometheus 指标 namespace/node_id 及服务地址, HTTP/gRPC 服务共用;
+// 未设置 PrometheusMetricHost/PrometheusMetricPort 时使用 HTTP 服务地址及端口+3000, 仅 gRPC 服务时使用 gRPC 服务地址及端口+3000
+func (c *LandauServer) preparePrometheus() {
+	prometheus.SetNamespace(c.PrometheusMetricNamespace)
+	prometheus.SetNodeId(c.PrometheusNodeId)
+	host, port := c.GRPCServiceAddress, c.GRPCServicePort
+	if c.HTTPServicePort > 0 {
+		host, port = c.HTTPServiceAddress, c.HTTPServicePort
+		if c.DynamicHTTPServiceAddress != nil {
+			host = c.DynamicHTTPServiceAddress()
+		}
+	}
+	if c.PrometheusMetricHost != "" {
+		host = c.PrometheusMetricHost
+	}
+	prometheus.SetServerHost(host)
+	if c.PrometheusMetricPort > 0 {
+		prometheus.SetServerPort(c.PrometheusMetricPort)
+	} else {
+		prometheus.SetServerPort(port + 3000)
+	}
+	if c.EnablePrometheusMetric {
+		go prometheus.StartApiMetric()
+	}
+}
+
+// prepareHTTP 构建 gin 引擎, 注册处理入口, 启动 keepalive 检查, 返回监听地址
+func (c *LandauServer) prepareHTTP(ctx context.Context) (string, string) {
+	c.ginRouter = gin.Default()
+	c.ginRouter.ContextWithFallback = true //*gin.Context 作为 context.Context 时使用 Request.Context() 的 deadline 及取消
+	if c.RegisterHTTPHandles != nil {
+		c.RegisterHTTPHandles()
+	}
+	if c.RegisterHTTPCustomHandles != nil {
+		c.RegisterHTTPCustomHandles(c.ginRouter)
+	}
+	if !c.DisableServiceHealthReceiver {
+		healthReceiverLog := func(response interface{}) string { return fmt.Sprintf("%v", response) }
+		api.AddExcludeServiceDisabled("ServiceHealthCheck")
+		api.AddExcludeServiceDisabled("/ServiceHealthCheck")
+		api.AddExcludeServiceDisabled("/output_keepalived_trace")
+		api.AddHTTPHandle("/ServiceHealthCheck", "ServiceHealthCheck", data.NewServiceHealthCheckRequest, data.DoHealthCheck, healthReceiverLog, "health_receiver")
+		c.ginRouter.GET("/output_keepalived_trace", data.OutputKeepaliveStatics)
+	}
+	if !c.DisableHealthProbe {
+		livenessPath, readinessPath := c.LivenessPath, c.ReadinessPath
+		if livenessPath == "" {
+			livenessPath = DefaultLivenessPath
+		}
+		if readinessPath == "" {
+			readinessPath = DefaultReadinessPath
+		}
+		c.ginRouter.GET(livenessPath, api.LivenessHandle)
+		c.ginRouter.GET(readinessPath, api.ReadinessHandle)
+	}
+	if c.OpenAPIPath != "" {
+		api.AddExcludeServiceDisabled(c.OpenAPIPath)
+		api.RegisterOpenAPIHandle(c.ginRouter, c.OpenAPIPath)
+	}
+	if c.EnableAdminAPI && c.AdminAPIPort <= 0 {
+		api.RegisterAdminAPI(c.ginRouter.Group(c.adminAPIPrefix()), c.AdminAPICheckACL)
+	}
+	api.DisableTraceServiceAddress = c.DisableTraceServiceAddress
+	api.EnableMonitorHttpAPI = c.EnableMonitorAPI
+	api.NotifyHttpAPIWeChatRobot = c.NotifyAPIWeChatRobot
+	data.ServiceName = c.ServiceName
+	data.ReceivedServiceCallback = c.ReceivedServiceCallback
+	api.SetPostBindingComplex(c.PostBindingComplex)
+	api.SetUnRegisterHandle(c.UnRegisterHTTPHandle)
+	api.RegisterHTTPHandle(c.ginRouter)
+	api.RegisterRestfulHTTPHandle(c.ginRouter)
+	if c.BatchActionPath != "" {
+		api.RegisterBatchActionHandle(c.ginRouter, c.BatchActionPath)
+	}
+	api.SetHTTPCheckACL(c.HTTPNeedCheckACL, c.HTTPCheckACL)
+	api.SetHTTPCustomLogTag(c.HTTPEnableCustomLogTag, c.HTTPCustomLog)
+	api.SetHTTPAuditLog(c.HTTPAuditLog)
+	if c.CORSPolicy != nil {
+		api.SetCORSPolicy(c.CORSPolicy)
+	}
+	if c.HandleTimeout > 0 {
+		api.SetHandleTimeout(time.Duration(c.HandleTimeout) * time.Second)
+	}
+	addr := c.HTTPServiceAddress
+	if c.DynamicHTTPServiceAddress != nil {
+		addr = c.DynamicHTTPServiceAddress()
+	}
+	if addr != "" && addr != "0.0.0.0" && addr != "::" {
+		data.LocalPrimaryAddress = addr
+		log.Info("[LocalPrimaryAddress] %s", data.LocalPrimaryAddress)
+	}
+	address := fmt.Sprintf("%s:%d", util.IPConvert(addr, util.IPV6Bracket), c.HTTPServicePort)
+	data.ServiceAddress = address
+	secondaryAddress := ""
+	if c.SecondaryServiceAddress != "" {
+		secondaryAddress = fmt.Sprintf("%s:%d", util.IPConvert(c.SecondaryServiceAddress, util.IPV6Bracket), c.HTTPServicePort)
+		data.SecondaryServiceAddress = secondaryAddress
+		if c.SecondaryServiceAddress != "0.0.0.0" && c.SecondaryServiceAddress != "::" {
+			data.LocalSecondaryAddress = c.SecondaryServiceAddress
+			log.Info("[LocalSecondaryAddress] %s", data.LocalSecondaryAddress)
+		}
+	}
+	if c.CheckServiceHealth != nil || c.CheckServiceHealth2 != nil {
+		if c.CheckServiceHealthPeriod > 0 {
+			data.MonitorServiceAddrPeriod = c.CheckServiceHealthPeriod
+		}
+		data.DisableAssignSourceIp = c.DisableCheckServiceHealthSourceIp
+		data.MonitorServiceAddrChange2 = c.CheckServiceHealth2
+		data.MonitorServiceAddrChange = c.CheckServiceHealth
+		data.RegisterServiceHealth()
+		go data.MonitorServiceHealthConfigsContext(ctx)
+		go data.StartHealthCheckingContext(ctx)
+	}
+	return address, secondaryAddress
+}
+
+// grpcInterceptorOptions 内置拦截器(日志及指标, ACL, ServiceDisabled)在前, 用户拦截器在后
+func (c *LandauServer) grpcInterceptorOptions() []grpc.ServerOption {
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if !c.DisableGRPCInterceptor {
+		api.SetGRPCCheckACL(c.GRPCCheckACL)
+		unary = append(unary, api.GRPCUnaryServerInterceptors()...)
+		stream = append(stream, api.GRPCStreamServerInterceptors()...)
+	}
+	unary = append(unary, c.GRPCUnaryInterceptors...)
+	stream = append(stream, c.GRPCStreamInterceptors...)
+	var opts []grpc.ServerOption
+	if len(unary) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(unary...))
+	}
+	if len(stream) > 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(stream...))
+	}
+	return opts
+}
+
+// adminAPIPrefix 管理接口路径前缀, 默认 /admin
+func (c *LandauServer) adminAPIPrefix() string {
+	if c.AdminAPIPrefix != "" {
+		return c.AdminAPIPrefix
+	}
+	return DefaultAdminAPIPrefix
+}
+
+// newGRPCServer 构建 gRPC 服务, 注册服务入口, grpc_health_v1 及 reflection
+func (c *LandauServer) newGRPCServer(opts []grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	c.RegisterGRPCHandle(s)
+	if _, registered := s.GetServiceInfo()[grpcHealthServiceName]; !c.DisableGRPCHealthServer && !registered {
+		api.RegisterGRPCHealthServer(s)
+	}
+	reflection.Register(s)
+	return s
+}
+
+// newHTTPServer 构建 HTTP 服务, GRPCOnHTTPPort 时同时提供 gRPC 及 h2c 服务
+func (c *LandauServer) newHTTPServer(address string, tlsConfig *tls.Config) (*http.Server, error) {
+	if c.httpMux != nil {
+		return newMuxHTTPServer(address, c.httpMux, tlsConfig)
+	}
+	return &http.Server{Addr: address, Handler: c.ginRouter, TLSConfig: tlsConfig}, nil
+}
+
+// abortRun 启动失败时停止已经启动的组件, 返回合并后的错误
+func (c *LandauServer) abortRun(err error) error {
+	errs := runErrors{err}
+	if shutdownErr := c.shutdown(c.GracefulTimeout); shutdownErr != nil {
+		errs = append(errs, shutdownErr)
+	}
+	return errs
+}
+
+// serveHTTP 设置了 TLSConfig 时以 TLS 方式服务, 证书由 TLSConfig 动态提供
+func serveHTTP(s *http.Server, l net.Listener) error {
+	var err error
+	if s.TLSConfig != nil {
+		err = s.ServeTLS(l, "", "")
+	} else {
+		err = s.Serve(l)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
 // StartCronJobMode 作为普通程序启动(仅仅运行cron job)
@@ -223,14 +417,14 @@ func (c *LandauServer) StartCronJobMode(gracefulTimeout uint64) {
 	if !c.DisableGracefulStopping && c.DynamicReloadConfig != nil {
 		reloadCallback = c.DynamicReloadConfig
 	}
-	if c.DestoryCallback != nil {
-		destoryCallback = c.DestoryCallback
-	}
-	makeReloadSignal()
+	c.runAdminCommand()
 	log.LoadLogConfig(c.LogConfig, c.DefaultLoggerName)
 	if c.CustomInit != nil {
 		c.CustomInit()
 	}
+	if err := c.startComponents(context.Background()); err != nil {
+		sysLog.Fatalf("[Engine] Start components error,err:%v", err)
+	}
 	if c.GetCronTasks != nil {
 		p, jobs := c.GetCronTasks()
 		util.StartCronJob(p, jobs)
@@ -239,9 +433,12 @@ func (c *LandauServer) StartCronJobMode(gracefulTimeout uint64) {
 			if gracefulTimeout == 0 {
 				gracefulTimeout = 60
 			}
-			gracefulStop(gracefulTimeout)
+			c.gracefulStop(gracefulTimeout)
 		}
 	}
+	if err := c.stopComponents(); err != nil { //未启动定时任务时直接退出
+		log.Error("[Engine] Stop components error,err:%v", err)
+	}
 	log.Close()
 }
 
@@ -255,14 +452,14 @@ func (c *LandauServer) StartNormalServerMode(mainEntry func(), gracefulTimeout u
 	if !c.DisableGracefulStopping && c.DynamicReloadConfig != nil {
 		reloadCallback = c.DynamicReloadConfig
 	}
-	if c.DestoryCallback != nil {
-		destoryCallback = c.DestoryCallback
-	}
-	makeReloadSignal()
+	c.runAdminCommand()
 	log.LoadLogConfig(c.LogConfig, c.DefaultLoggerName)
 	if c.CustomInit != nil {
 		c.CustomInit()
 	}
+	if err := c.startComponents(context.Background()); err != nil {
+		sysLog.Fatalf("[Engine] Start components error,err:%v", err)
+	}
 	if c.GetCronTasks != nil {
 		p, jobs := c.GetCronTasks()
 		util.StartCronJob(p, jobs)
@@ -272,7 +469,7 @@ func (c *LandauServer) StartNormalServerMode(mainEntry func(), gracefulTimeout u
 	if gracefulTimeout == 0 {
 		gracefulTimeout = 60
 	}
-	gracefulStop(gracefulTimeout)
+	c.gracefulStop(gracefulTimeout)
 	log.Close()
 }
 
@@ -292,125 +489,168 @@ func (c *LandauServer) StartNormalMode(mainEntry func()) {
 	log.Close()
 }
 
-func gracefulStop(gracefulTimeout uint64) {
+// gracefulStop 等待退出信号或 admin stop 命令后停止所有组件
+func (c *LandauServer) gracefulStop(gracefulTimeout uint64) {
+	ctx, cancel := signalContext()
+	defer cancel()
+	closeAdminControl := c.startAdminControl(cancel)
+	<-ctx.Done()
+	closeAdminControl()
+	if err := c.shutdown(gracefulTimeout); err != nil {
+		sysLog.Fatalf("[Engine] Shutdown error,err:%v", err)
+	}
+	log.Info("[Engine] Shutdown Server completed.")
+}
+
+// signalContext 返回收到退出信号时取消的 context, 期间收到 SIGUSR1 信号则调用 reloadCallback
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	monitorSignal := make(chan os.Signal, 1)
+	if reloadCallback != nil {
+		signal.Notify(monitorSignal, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGUSR1)
+	} else {
+		signal.Notify(monitorSignal, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	}
+	go func() {
+		defer signal.Stop(monitorSignal)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case i := <-monitorSignal:
+				switch i {
+				case syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
+					log.Info("[Engine] receive exit signal %s, Shutdown Server ...", i.String())
+					cancel()
+					return
+				case syscall.SIGUSR1:
+					log.Info("[Engine] receive usr1 signal, dispatch reload event now")
+					if reloadCallback != nil {
+						reloadCallback()
+					}
+				}
+			}
+		}
+	}()
+	return ctx, cancel
+}
+
+// shutdown 分阶段停止: 标记 not ready 并通知 keepalive 对端, 等待流量切走及处理中的请求完成,
+// 再并行停止 HTTP/gRPC/cron 及 DestoryCallback, 最后逆序停止组件, 返回合并后的错误
+func (c *LandauServer) shutdown(gracefulTimeout uint64) error {
 	waitMaxSecond := gracefulTimeout
 	if waitMaxSecond == 0 {
 		waitMaxSecond = 60
 	}
-	waitingShutdownServer := func() {
-		wg := sync.WaitGroup{}
-		httpSrvShutdown := func() {
-			defer wg.Done()
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(waitMaxSecond))
-			defer cancel()
-			if srv != nil {
-				if err := srv.Shutdown(ctx); err != nil {
-					sysLog.Fatalf("[HTTP] Server Shutdown error,err:%v", err)
-				}
+	api.SetServiceShuttingDown(true) //readiness 返回 503, gRPC health 返回 NOT_SERVING
+	data.NotifyCheckerShutdown()
+	var (
+		wg     sync.WaitGroup
+		httpWg sync.WaitGroup
+		locker sync.Mutex
+		errs   runErrors
+	)
+	collect := func(err error) {
+		if err != nil {
+			locker.Lock()
+			errs = append(errs, err)
+			locker.Unlock()
+		}
+	}
+	if !c.DisableGracefulStopping {
+		c.drain(waitMaxSecond)
+	}
+	httpSrvShutdown := func(name string, s *http.Server) {
+		defer wg.Done()
+		defer httpWg.Done()
+		if s == nil {
+			return
+		}
+		if c.DisableGracefulStopping {
+			if err := s.Close(); err != nil {
+				collect(fmt.Errorf("[%s] Server Close error: %w", name, err))
 			}
+			return
 		}
-		secondHttpSrvShutdown := func() {
-			defer wg.Done()
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(waitMaxSecond))
-			defer cancel()
-			if secondSrv != nil {
-				if err := secondSrv.Shutdown(ctx); err != nil {
-					sysLog.Fatalf("[HTTP] Server Shutdown error,err:%v", err)
-				}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(waitMaxSecond))
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			collect(fmt.Errorf("[%s] Server Shutdown error: %w", name, err))
+		}
+	}
+	cronJobShutdown := func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(waitMaxSecond))
+		defer cancel()
+		if err := util.CronJobShutdown(ctx); err != nil {
+			collect(fmt.Errorf("[CronJobManager] Shutdown error: %w", err))
+		}
+	}
+	grpcSvrShutdown := func() {
+		defer wg.Done()
+		if c.grpcServer != nil {
+			if c.DisableGracefulStopping {
+				c.grpcServer.Stop()
+			} else {
+				c.grpcServer.GracefulStop()
 			}
 		}
-		cronJobShutdown := func() {
-			defer wg.Done()
+	}
+	muxShutdown := func() { //单端口模式: ServeHTTP 方式的 gRPC 不支持 GracefulStop, 等待 HTTP 停止监听且处理中的请求完成后 Stop
+		defer wg.Done()
+		if !c.DisableGracefulStopping {
+			httpWg.Wait()
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(waitMaxSecond))
 			defer cancel()
-			if err := util.CronJobShutdown(ctx); err != nil {
-				sysLog.Fatalf("[CronJobManager] Shutdown error,err:%v", err)
-			}
-		}
-		grpcSvrShutdown := func() {
-			defer wg.Done()
-			if grpcServer != nil {
-				grpcServer.GracefulStop()
+			if err := c.httpMux.wait(ctx); err != nil {
+				collect(fmt.Errorf("[HTTP-gRPC] wait in-flight requests error: %w", err))
 			}
 		}
-		appShutdown := func() {
-			defer wg.Done()
-			if err := appShutdownCallback(waitMaxSecond); err != nil {
-				sysLog.Fatalf("[appShutdownCallback] Shutdown error,err:%v", err)
-			}
+		if c.grpcServer != nil {
+			c.grpcServer.Stop()
 		}
-
-		wg.Add(5)
-		go httpSrvShutdown()
-		go secondHttpSrvShutdown()
-		go cronJobShutdown()
-		go grpcSvrShutdown()
-		go appShutdown()
-		data.NotifyCheckerShutdown()
-		wg.Wait()
-	}
-	monitorSignal := make(chan os.Signal)
-	if reloadCallback != nil {
-		signal.Notify(monitorSignal, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGUSR1)
-	} else {
-		signal.Notify(monitorSignal, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	}
-	for i := range monitorSignal {
-		switch i {
-		case syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT:
-			log.Info("[Engine] receive exit signal %s, Shutdown Server ...", i.String())
-			waitingShutdownServer()
-			log.Info("[Engine] Shutdown Server completed.")
-			return
-		case syscall.SIGUSR1:
-			log.Info("[Engine] receive usr1 signal, dispatch reload event now")
-			if reloadCallback != nil {
-				reloadCallback()
-			}
+	appShutdown := func() {
+		defer wg.Done()
+		if err := appShutdownCallback(c.DestoryCallback, waitMaxSecond); err != nil {
+			collect(fmt.Errorf("[appShutdownCallback] Shutdown error: %w", err))
 		}
 	}
-}
 
-func makeReloadSignal() {
-	if *reload {
-		if pid := os.Getegid(); pid != -1 {
-			appName := os.Args[0]
-			if runtime.GOOS == "windows" {
-				appName = strings.Replace(appName, "\\", "/", -1)
-			}
-			if i := strings.LastIndex(appName, "/"); i > 0 {
-				appName = appName[i+1:]
-			}
-			appFullPath := os.Args[0]
-			cmd := exec.Command("ps", "-e")
-			if out, err := cmd.CombinedOutput(); err == nil {
-				processes := strings.Split(string(out), "\n")
-				for _, process := range processes {
-					if id, n, err := getProcIdName(process); err == nil && id != pid && (n == appName || n == appFullPath) {
-						_ = syscall.Kill(id, syscall.SIGUSR1)
-						break
-					}
-				}
-			}
-		}
-		os.Exit(0)
+	wg.Add(6)
+	httpWg.Add(3)
+	go httpSrvShutdown("HTTP", c.httpServer)
+	go httpSrvShutdown("HTTP-Secondary", c.secondHttpServer)
+	go httpSrvShutdown("HTTP-Admin", c.adminHttpServer)
+	go cronJobShutdown()
+	if c.httpMux != nil {
+		go muxShutdown()
+	} else {
+		go grpcSvrShutdown()
 	}
+	go appShutdown()
+	wg.Wait()
+	collect(c.stopComponents())
+	return errs.errorOrNil()
 }
 
-func getProcIdName(process string) (int, string, error) {
-	p := strings.TrimSpace(process)
-	lines := strings.Split(p, " ")
-	if len(lines) >= 4 {
-		pid, err := strconv.Atoi(lines[0])
-		if err != nil {
-			return -1, "", err
-		}
-		return pid, lines[len(lines)-1], nil
+// drain 等待 ShutdownPropagationDelay 秒使对端切走流量, 停止 MQ 消费者接收新消息, 再等待处理中的 HTTP/gRPC 请求及 MQ 消息完成(最长 waitMaxSecond 秒);
+// 等待超时仅记录警告, 继续关闭流程
+func (c *LandauServer) drain(waitMaxSecond uint64) {
+	if c.ShutdownPropagationDelay > 0 {
+		log.Info("[Engine] wait %d seconds for propagation of shutting down...", c.ShutdownPropagationDelay)
+		time.Sleep(time.Second * time.Duration(c.ShutdownPropagationDelay))
+	}
+	data.StopRabbitMQConsumers()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(waitMaxSecond))
+	defer cancel()
+	log.Info("[Engine] wait in-flight requests:%v", data.GetInFlight())
+	if err := data.WaitInFlightZero(ctx); err != nil {
+		log.Warn("[Engine] wait in-flight requests error: %v, in-flight:%v", err, data.GetInFlight())
 	}
-	return -1, "", fmt.Errorf("pare proceess (%s) invalid", p)
 }
 
-func appShutdownCallback(waitMaxSecond uint64) error {
+func appShutdownCallback(destoryCallback func(), waitMaxSecond uint64) error {
 	if destoryCallback != nil {
 		pollIntervalBase := time.Millisecond
 		shutdownPollIntervalMax := 500 * time.Millisecond
@@ -423,18 +663,20 @@ func appShutdownCallback(waitMaxSecond uint64) error {
 			return interval
 		}
 		timer := time.NewTimer(nextPollInterval())
-		callbackDone, start := false, time.Now().Unix()
+		defer timer.Stop()
+		callbackDone, start := make(chan struct{}), time.Now().Unix()
 		go func() {
 			log.Info("[Engine] call destoryCallback...")
 			destoryCallback()
-			callbackDone = true
+			close(callbackDone)
 			log.Info("[Engine] call destoryCallback done")
 		}()
 		for {
-			if callbackDone { //等待 destoryCallback 完成
+			select {
+			case <-callbackDone: //等待 destoryCallback 完成
 				return nil
+			case <-timer.C:
 			}
-			<-timer.C
 			if time.Now().Unix()-start > int64(waitMaxSecond) {
 				return fmt.Errorf("wait destoryCallback time out")
 			}
@@ -0,0 +1,66 @@
+package entry
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+type (
+	//_MuxHandler 单端口同时提供 HTTP 及 gRPC 服务: HTTP/2 且 content-type 为 application/grpc 的请求交由 gRPC 处理, 其余交由 gin
+	_MuxHandler struct {
+		httpHandler http.Handler //gin 引擎
+		grpcServer  *grpc.Server //gRPC 服务, 为空时全部交由 httpHandler
+		active      int64        //处理中的请求数, h2c 连接被 hijack 后 http.Server.Shutdown 无法跟踪, 由此计数等待
+	}
+)
+
+const (
+	grpcContentType = "application/grpc"
+)
+
+func (h *_MuxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&h.active, 1)
+	defer atomic.AddInt64(&h.active, -1)
+	if h.grpcServer != nil && r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType) {
+		h.grpcServer.ServeHTTP(w, r)
+		return
+	}
+	h.httpHandler.ServeHTTP(w, r)
+}
+
+// wait 等待处理中的请求完成, 超时返回 ctx.Err()
+func (h *_MuxHandler) wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&h.active) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// newMuxHTTPServer 构建支持 HTTP/2 的服务, 未启用 TLS 时支持 h2c(明文 HTTP/2);
+// Shutdown 时向 HTTP/2 连接发送 GOAWAY
+func newMuxHTTPServer(address string, h *_MuxHandler, tlsConfig *tls.Config) (*http.Server, error) {
+	s := &http.Server{Addr: address, Handler: h, TLSConfig: tlsConfig}
+	h2s := &http2.Server{}
+	if err := http2.ConfigureServer(s, h2s); err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		s.TLSConfig = nil //ConfigureServer 会生成空的 TLSConfig, serveHTTP 依此判断是否启用 TLS
+		s.Handler = h2c.NewHandler(h, h2s)
+	}
+	return s, nil
+}
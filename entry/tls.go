@@ -0,0 +1,129 @@
+package entry
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/NeilXu2017/landau/helper"
+	"github.com/NeilXu2017/landau/log"
+)
+
+type (
+	//TLSConfig 服务端证书配置, 证书文件在 reload 信号(SIGUSR1)时重新加载, 已建立的连接不受影响
+	TLSConfig struct {
+		CertFile          string //证书文件
+		KeyFile           string //私钥文件
+		ClientCAFile      string //校验客户端证书的 CA 文件, 设置后启用 mutual TLS
+		RequireClientCert bool   //是否强制客户端提供证书, false 时客户端提供证书才校验
+	}
+	_CertReloader struct {
+		name      string
+		conf      TLSConfig
+		locker    sync.RWMutex
+		cert      *tls.Certificate
+		clientCAs *x509.CertPool
+	}
+)
+
+var (
+	httpNextProtos = []string{"h2", "http/1.1"}
+	grpcNextProtos = []string{"h2"}
+)
+
+func newCertReloader(name string, conf TLSConfig) (*_CertReloader, error) {
+	r := &_CertReloader{name: name, conf: conf}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload 重新加载证书, 失败时保留原证书
+func (r *_CertReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(helper.ConvertAbsolutePath(r.conf.CertFile), helper.ConvertAbsolutePath(r.conf.KeyFile))
+	if err != nil {
+		return fmt.Errorf("[%s] load certificate error: %w", r.name, err)
+	}
+	var clientCAs *x509.CertPool
+	if r.conf.ClientCAFile != "" {
+		b, err := os.ReadFile(helper.ConvertAbsolutePath(r.conf.ClientCAFile))
+		if err != nil {
+			return fmt.Errorf("[%s] read client CA error: %w", r.name, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(b) {
+			return fmt.Errorf("[%s] no valid certificate in client CA file %s", r.name, r.conf.ClientCAFile)
+		}
+	}
+	r.locker.Lock()
+	r.cert, r.clientCAs = &cert, clientCAs
+	r.locker.Unlock()
+	log.Info("[TLS] [%s] certificate loaded, cert:%s key:%s clientCA:%s", r.name, r.conf.CertFile, r.conf.KeyFile, r.conf.ClientCAFile)
+	return nil
+}
+
+// tlsConfig 每次握手取当前证书生成配置, nextProtos 为 ALPN 协议列表
+func (r *_CertReloader) tlsConfig(nextProtos []string) *tls.Config {
+	current := func() *tls.Config {
+		r.locker.RLock()
+		defer r.locker.RUnlock()
+		cfg := &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{*r.cert},
+			NextProtos:   nextProtos,
+		}
+		if r.clientCAs != nil {
+			cfg.ClientCAs = r.clientCAs
+			cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			if r.conf.RequireClientCert {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+		}
+		return cfg
+	}
+	cfg := current()
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) { return current(), nil }
+	return cfg
+}
+
+// ReloadCertificates 重新加载 HTTP/gRPC 服务证书, Start 启动时收到 SIGUSR1 信号自动调用
+func (c *LandauServer) ReloadCertificates() error {
+	var errs runErrors
+	for _, r := range c.certReloaders {
+		if err := r.reload(); err != nil {
+			log.Error("[TLS] %v", err)
+			errs = append(errs, err)
+		}
+	}
+	return errs.errorOrNil()
+}
+
+func (c *LandauServer) isTLSEnabled() bool {
+	return c.HTTPTLS != nil || c.SecondaryHTTPTLS != nil || c.GRPCTLS != nil
+}
+
+// newTLSConfig 构建可重新加载证书的 tls.Config, conf 为 nil 时返回 nil
+func (c *LandauServer) newTLSConfig(name string, conf *TLSConfig, nextProtos []string) (*tls.Config, error) {
+	if conf == nil {
+		return nil, nil
+	}
+	r, err := newCertReloader(name, *conf)
+	if err != nil {
+		return nil, err
+	}
+	c.certReloaders = append(c.certReloaders, r)
+	return r.tlsConfig(nextProtos), nil
+}
+
+// reloadConfig 处理 reload 信号: 重新加载证书, 再调用 DynamicReloadConfig
+func (c *LandauServer) reloadConfig() {
+	if len(c.certReloaders) > 0 {
+		_ = c.ReloadCertificates()
+	}
+	if c.DynamicReloadConfig != nil {
+		c.DynamicReloadConfig()
+	}
+}
@@ -201,6 +201,9 @@ func _apiLoggerParse(logText string) map[string]interface{} {
 			params["response"] = responseParam
 		}
 	}
+	if arrayLen > 5 && strings.HasPrefix(logArray[5], "RequestId:") {
+		params["request_id"] = strings.TrimPrefix(logArray[5], "RequestId:")
+	}
 	return params
 }
 
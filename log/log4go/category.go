@@ -18,7 +18,7 @@ func LOGGER(category string) *Filter {
 }
 
 func (f *Filter) intLogf(lvl Level, format string, args ...interface{}) {
-	if lvl >= f.Level {
+	if lvl >= f.GetLevel() {
 		pc, _, lineno, ok := runtime.Caller(3)
 		src := ""
 		if ok {
@@ -32,14 +32,14 @@ func (f *Filter) intLogf(lvl Level, format string, args ...interface{}) {
 		if f.Category != "DEFAULT" && f.Category != "stdout" {
 			f.LogWrite(rec)
 		}
-		if defaultFilter := Global["stdout"]; defaultFilter != nil && lvl >= defaultFilter.Level {
+		if defaultFilter := Global["stdout"]; defaultFilter != nil && lvl >= defaultFilter.GetLevel() {
 			defaultFilter.LogWrite(rec)
 		}
 	}
 }
 
 func (f *Filter) intLogc(lvl Level, closure func() string) {
-	if lvl >= f.Level {
+	if lvl >= f.GetLevel() {
 		pc, _, lineno, ok := runtime.Caller(3)
 		src := ""
 		if ok {
@@ -49,19 +49,19 @@ func (f *Filter) intLogc(lvl Level, closure func() string) {
 		if f.Category != "DEFAULT" && f.Category != "stdout" {
 			f.LogWrite(rec)
 		}
-		if defaultFilter := Global["stdout"]; defaultFilter != nil && lvl > defaultFilter.Level {
+		if defaultFilter := Global["stdout"]; defaultFilter != nil && lvl > defaultFilter.GetLevel() {
 			defaultFilter.LogWrite(rec)
 		}
 	}
 }
 
 func (f *Filter) Log(lvl Level, source, message string) {
-	if lvl >= f.Level {
+	if lvl >= f.GetLevel() {
 		rec := &LogRecord{Level: lvl, Created: time.Now(), Source: source, Message: message, Category: f.Category}
 		if f.Category != "DEFAULT" && f.Category != "stdout" {
 			f.LogWrite(rec)
 		}
-		if defaultFilter := Global["stdout"]; defaultFilter != nil && lvl > defaultFilter.Level {
+		if defaultFilter := Global["stdout"]; defaultFilter != nil && lvl > defaultFilter.GetLevel() {
 			defaultFilter.LogWrite(rec)
 		}
 	}
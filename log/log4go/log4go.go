@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -32,11 +33,11 @@ type (
 		Close()
 	}
 	Filter struct {
-		Level Level
+		Level Level // Use GetLevel/SetLevel when it may change while logging
 		LogWriter
 		Category string
 	}
-	Level  int
+	Level  int32
 	Logger map[string]*Filter
 )
 
@@ -56,6 +57,14 @@ func NewDefaultLogger(lvl Level) Logger {
 	return Logger{"stdout": &Filter{lvl, NewConsoleLogWriter(), "DEFAULT"}}
 }
 
+func (f *Filter) GetLevel() Level {
+	return Level(atomic.LoadInt32((*int32)(&f.Level)))
+}
+
+func (f *Filter) SetLevel(lvl Level) {
+	atomic.StoreInt32((*int32)(&f.Level), int32(lvl))
+}
+
 func (log Logger) Close() {
 	for name, f := range log {
 		f.Close()
@@ -75,7 +84,7 @@ func (log Logger) AddFilter(name string, lvl Level, writer LogWriter, category .
 func (log Logger) Log(lvl Level, source, message string) {
 	rec := &LogRecord{Level: lvl, Created: time.Now(), Source: source, Message: message}
 	for _, filter := range log {
-		if lvl >= filter.Level {
+		if lvl >= filter.GetLevel() {
 			filter.LogWrite(rec)
 		}
 	}
@@ -1,15 +1,27 @@
 package log
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/NeilXu2017/landau/log/log4go"
+)
+
 var (
 	_CategoryInfoLogLevel = map[string]int{
 		"HTTP":    1,
 		"SQL":     1,
 		"ExecCMD": 1,
 	}
+	_syncCategoryInfoLog = sync.RWMutex{}
+	_log4goLevelNames    = []string{"FINEST", "FINE", "DEBUG", "TRACE", "INFO", "WARNING", "ERROR", "CRITICAL"} //与 log4go.Level 顺序一致
 )
 
 // IsEnableCategoryInfoLog 检测Info日志是否需要记录
 func IsEnableCategoryInfoLog(category string) bool {
+	_syncCategoryInfoLog.RLock()
+	defer _syncCategoryInfoLog.RUnlock()
 	return _CategoryInfoLogLevel[category] == 1
 }
 
@@ -22,6 +34,55 @@ func SetCategoryInfoLogOption(category string, enable bool) {
 	_SetCategoryInfoLog(category, infoLeve)
 }
 
+// GetCategoryInfoLogOptions 返回各 category Info日志记录选项
+func GetCategoryInfoLogOptions() map[string]bool {
+	_syncCategoryInfoLog.RLock()
+	defer _syncCategoryInfoLog.RUnlock()
+	options := make(map[string]bool, len(_CategoryInfoLogLevel))
+	for category, infoLeve := range _CategoryInfoLogLevel {
+		options[category] = infoLeve == 1
+	}
+	return options
+}
+
+// GetLoggerLevels 返回配置的 logger 及其日志级别, 级别名称与配置文件一致
+func GetLoggerLevels() map[string]string {
+	levels := make(map[string]string, len(log4go.Global))
+	for name, f := range log4go.Global {
+		levels[name] = _log4goLevelName(f.GetLevel())
+	}
+	return levels
+}
+
+// SetLoggerLevel 运行时修改 logger 日志级别, level 取值与配置文件一致: DEBUG/INFO/WARNING/ERROR 等
+func SetLoggerLevel(logger string, level string) error {
+	lvl := -1
+	for i, name := range _log4goLevelNames {
+		if name == strings.ToUpper(level) {
+			lvl = i
+			break
+		}
+	}
+	if lvl < 0 {
+		return fmt.Errorf("unknown log level: %s", level)
+	}
+	f, ok := log4go.Global[logger]
+	if !ok {
+		return fmt.Errorf("logger %s not found", logger)
+	}
+	f.SetLevel(log4go.Level(lvl))
+	return nil
+}
+
 func _SetCategoryInfoLog(category string, infoLeve int) {
+	_syncCategoryInfoLog.Lock()
+	defer _syncCategoryInfoLog.Unlock()
 	_CategoryInfoLogLevel[category] = infoLeve
 }
+
+func _log4goLevelName(lvl log4go.Level) string {
+	if lvl < 0 || int(lvl) >= len(_log4goLevelNames) {
+		return lvl.String()
+	}
+	return _log4goLevelNames[lvl]
+}
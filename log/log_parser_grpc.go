@@ -39,7 +39,7 @@ func (c _GRPCDataLogParser) Parse(logText string) (map[string]interface{}, bool)
 		}
 		if arrayLen > 5 {
 			if logArray[5] != "" {
-				params["error"] = strings.TrimLeft(logArray[4], "Error:")
+				params["error"] = strings.TrimLeft(logArray[5], "Error:")
 			}
 		}
 	}
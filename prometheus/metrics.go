@@ -19,19 +19,26 @@ type (
 		Help   string
 		Enable bool
 	}
-	GetExtraLabelValueFunc func(string, string, *http.Request, interface{}, *gin.Context) []string
+	GetExtraLabelValueFunc     func(string, string, *http.Request, interface{}, *gin.Context) []string
+	GetGRPCExtraLabelValueFunc func(fullMethod string, req interface{}, rsp interface{}) []string
+)
+
+const (
+	//CacheNone 未使用应答缓存时 cache 标签的值
+	CacheNone = "none"
 )
 
 var (
-	_PrometheusServerHost       string                                                                  //Prometheus服务接口地址:IP
-	_PrometheusServerPort       int                                                                     //Prometheus服务接口地址:端口
-	_namespace                  string                                                                  //metric namespace, fqName prefix
-	_node_id                    string                                                                  //metric label node_id
-	_metricUri                  = "metrics"                                                             //metric handle 地址
-	_pprofUri                   = "pprof"                                                               //pprof handle 地址
-	_VariableLabels             = []string{"ret_code", "action", "method", "uri", "service", "node_id"} //缺省variable label tag
-	_ExtraLabels                = []string{}                                                            //extra lables
-	_GetExtraLabelValue         GetExtraLabelValueFunc                                                  //func of extra lable value
+	_PrometheusServerHost       string                                                                           //Prometheus服务接口地址:IP
+	_PrometheusServerPort       int                                                                              //Prometheus服务接口地址:端口
+	_namespace                  string                                                                           //metric namespace, fqName prefix
+	_node_id                    string                                                                           //metric label node_id
+	_metricUri                  = "metrics"                                                                      //metric handle 地址
+	_pprofUri                   = "pprof"                                                                        //pprof handle 地址
+	_VariableLabels             = []string{"ret_code", "action", "method", "uri", "service", "node_id", "cache"} //缺省variable label tag, cache 为应答缓存结果 hit/stale/miss/none
+	_ExtraLabels                = []string{}                                                                     //extra lables
+	_GetExtraLabelValue         GetExtraLabelValueFunc                                                           //func of extra lable value
+	_GetGRPCExtraLabelValue     GetGRPCExtraLabelValueFunc                                                       //func of gRPC extra lable value
 	customPrometheusCollector   []prometheus.Collector
 	_DefaultPrometheusCollector = []DescTag{
 		{
@@ -49,10 +56,28 @@ var (
 			Help:   "HTTP request latencies in seconds",
 			Enable: true,
 		},
+		{
+			Name:   "http_request_rate_limited_total",
+			Help:   "Total number of HTTP requests rejected by rate limit",
+			Enable: true,
+		},
+		{
+			Name:   "http_request_timeout_total",
+			Help:   "Total number of HTTP requests exceeding handle timeout",
+			Enable: true,
+		},
+		{
+			Name:   "http_request_deprecated_total",
+			Help:   "Total number of HTTP requests calling deprecated action versions",
+			Enable: true,
+		},
 	}
 	uptime      *prometheus.CounterVec   //上线时长
 	reqCount    *prometheus.CounterVec   //API请求次数
 	reqDuration *prometheus.HistogramVec //API请求耗时分布
+	rateLimited *prometheus.CounterVec   //API限流拒绝次数
+	timeouts    *prometheus.CounterVec   //API处理超时次数
+	deprecated  *prometheus.CounterVec   //已弃用 Action 版本调用次数
 )
 
 func SetServerHost(addr string)     { _PrometheusServerHost = addr } //从LandauServer 配置获取,无法直接调用设置
@@ -66,6 +91,11 @@ func SetExtraLabel(extraLabe []string, f GetExtraLabelValueFunc) { //设置extra
 	_GetExtraLabelValue = f
 }
 
+// SetGRPCExtraLabelValue 设置 gRPC 请求的 extra label value function, 未设置时 extra label 取空值 需要在 LandauServer.Start()前调用
+func SetGRPCExtraLabelValue(f GetGRPCExtraLabelValueFunc) {
+	_GetGRPCExtraLabelValue = f
+}
+
 // SetVariableLabels 设置内置3个变量Tag名称(需要按照顺序修改):默认是 ret_code,action,method和uri 需要在 LandauServer.Start()前调用
 func SetVariableLabels(labels ...string) {
 	n, m := len(_VariableLabels), len(labels)
@@ -120,6 +150,21 @@ func StartApiMetric() {
 				reqDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: _namespace, Name: dc.Name, Help: dc.Help}, labelNames)
 				pcs = append(pcs, reqDuration)
 			}
+		case 3:
+			if dc.Enable {
+				rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: _namespace, Name: dc.Name, Help: dc.Help}, []string{"rule", "action", "uri", "service", "node_id"})
+				pcs = append(pcs, rateLimited)
+			}
+		case 4:
+			if dc.Enable {
+				timeouts = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: _namespace, Name: dc.Name, Help: dc.Help}, []string{"action", "uri", "service", "node_id"})
+				pcs = append(pcs, timeouts)
+			}
+		case 5:
+			if dc.Enable {
+				deprecated = prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: _namespace, Name: dc.Name, Help: dc.Help}, []string{"action", "version", "service", "node_id"})
+				pcs = append(pcs, deprecated)
+			}
 		}
 	}
 	pcs = append(pcs, customPrometheusCollector...)
@@ -134,12 +179,26 @@ func StartApiMetric() {
 	fmt.Printf("[StartApiMetric] server is shutdown")
 }
 
-// UpdateApiMetric 框架调用,记录指标
+// UpdateApiMetric 框架调用,记录指标, cache 标签为 none
 func UpdateApiMetric(code int, action string, tStart time.Time, r *http.Request, uri string, extraValues []string) {
+	UpdateApiCacheMetric(code, action, tStart, r, uri, CacheNone, extraValues)
+}
+
+// UpdateApiCacheMetric 框架调用,记录指标, cache 为应答缓存结果 hit/stale/miss, 未使用缓存时为 none
+func UpdateApiCacheMetric(code int, action string, tStart time.Time, r *http.Request, uri string, cache string, extraValues []string) {
 	if uri == "" {
 		uri = r.URL.Path
 	}
-	lvs := []string{strconv.Itoa(code), action, r.Method, uri, _namespace, _node_id}
+	updateMetric(code, action, r.Method, uri, cache, tStart, extraValues)
+}
+
+// UpdateGRPCMetric 框架调用,记录 gRPC 指标: method 标签为 GRPC, uri 标签为 gRPC FullMethod, cache 标签为 none
+func UpdateGRPCMetric(code int, action string, tStart time.Time, fullMethod string, extraValues []string) {
+	updateMetric(code, action, "GRPC", fullMethod, CacheNone, tStart, extraValues)
+}
+
+func updateMetric(code int, action string, method string, uri string, cache string, tStart time.Time, extraValues []string) {
+	lvs := []string{strconv.Itoa(code), action, method, uri, _namespace, _node_id, cache}
 	lvs = append(lvs, extraValues...)
 	if reqCount != nil {
 		reqCount.WithLabelValues(lvs...).Inc()
@@ -149,6 +208,35 @@ func UpdateApiMetric(code int, action string, tStart time.Time, r *http.Request,
 	}
 }
 
+// UpdateRateLimitMetric 框架调用,记录限流拒绝次数
+func UpdateRateLimitMetric(rule string, action string, uri string) {
+	if rateLimited != nil {
+		rateLimited.WithLabelValues(rule, action, uri, _namespace, _node_id).Inc()
+	}
+}
+
+// UpdateHandleTimeoutMetric 框架调用,记录处理超时次数
+func UpdateHandleTimeoutMetric(action string, uri string) {
+	if timeouts != nil {
+		timeouts.WithLabelValues(action, uri, _namespace, _node_id).Inc()
+	}
+}
+
+// UpdateDeprecatedMetric 框架调用,记录已弃用 Action 版本调用次数
+func UpdateDeprecatedMetric(action string, version string) {
+	if deprecated != nil {
+		deprecated.WithLabelValues(action, version, _namespace, _node_id).Inc()
+	}
+}
+
+// GetGRPCExtraLabelValue 框架调用,获取 gRPC 请求的 extra lable value
+func GetGRPCExtraLabelValue(fullMethod string, req interface{}, rsp interface{}) []string {
+	if _GetGRPCExtraLabelValue != nil {
+		return _GetGRPCExtraLabelValue(fullMethod, req, rsp)
+	}
+	return make([]string, len(_ExtraLabels))
+}
+
 // Extra lable value 框架调用,获取extra lable value
 func GetExtraLabelValue(action string, url string, r *http.Request, rsp interface{}, c *gin.Context) []string {
 	if _GetExtraLabelValue != nil {
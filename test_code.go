@@ -94,7 +94,7 @@ func dbTest() {
 	test.InitLog()
 	defer log.Close()
 	if *dbIp == "" {
-		fmt.Println("Missing database ip\n")
+		fmt.Println("Missing database ip")
 		return
 	}
 	extendProrety := make(map[string]interface{})
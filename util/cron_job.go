@@ -2,10 +2,13 @@ package util
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/NeilXu2017/landau/log"
@@ -27,6 +30,21 @@ type (
 		JobRunningState           map[string]bool
 		SyncJobRunningStateLocker sync.RWMutex
 	}
+	//CronJobInfo 定时任务运行状态
+	CronJobInfo struct {
+		Name     string //Job 名称
+		Schedule string //定时安排
+		Paused   bool   //是否暂停
+		Running  bool   //是否正在执行
+		Prev     string //上次执行时间
+		Next     string //下次执行时间
+	}
+	_CronJob struct {
+		name     string
+		schedule string
+		run      func()
+		paused   int32
+	}
 )
 
 const shutdownPollIntervalMax = 500 * time.Millisecond
@@ -35,8 +53,19 @@ var (
 	engine            *cron.Cron
 	s                 *CronJobManager
 	ScheduledJobCount = uint(0)
+	cronJobs          = make(map[string]*_CronJob) //key: Job 名称
+	syncCronJobs      = sync.RWMutex{}
 )
 
+// Run 实现 cron.Job, 暂停时跳过
+func (j *_CronJob) Run() {
+	if atomic.LoadInt32(&j.paused) == 1 {
+		log.Info("[CronJobManager] [%s] paused,skip once.", j.name)
+		return
+	}
+	j.run()
+}
+
 func (c *CronJobManager) GetJobRunningState(jobName string) bool {
 	c.SyncJobRunningStateLocker.RLock()
 	defer c.SyncJobRunningStateLocker.RUnlock()
@@ -110,7 +139,11 @@ func StartCronJob(p interface{}, jobs []SingletonCronTask) {
 						log.Error("[CronJobManager] [%s] missing job function.", n)
 					}
 				}
-				_ = engine.AddFunc(t.Schedule, jobFuncProxy)
+				job := &_CronJob{name: n, schedule: t.Schedule, run: jobFuncProxy}
+				_ = engine.AddJob(t.Schedule, job)
+				syncCronJobs.Lock()
+				cronJobs[n] = job
+				syncCronJobs.Unlock()
 				ScheduledJobCount++
 				log.Info("[CronJobManager] %s ADD,schedule:%s", t.Name, t.Schedule)
 				if t.Immediate {
@@ -154,3 +187,71 @@ func CronJobShutdown(ctx context.Context) error {
 	}
 	return nil
 }
+
+// GetCronJobs 返回已启动的定时任务, 按名称排序
+func GetCronJobs() []CronJobInfo {
+	var jobs []CronJobInfo
+	if engine == nil {
+		return jobs
+	}
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05")
+	}
+	for _, e := range engine.Entries() {
+		if j, ok := e.Job.(*_CronJob); ok {
+			jobs = append(jobs, CronJobInfo{
+				Name:     j.name,
+				Schedule: j.schedule,
+				Paused:   atomic.LoadInt32(&j.paused) == 1,
+				Running:  s.GetJobRunningState(j.name),
+				Prev:     formatTime(e.Prev),
+				Next:     formatTime(e.Next),
+			})
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// PauseCronJob 暂停定时任务, 暂停期间到达执行时间时跳过, 不影响正在执行的任务
+func PauseCronJob(name string) error {
+	return setCronJobPaused(name, 1)
+}
+
+// ResumeCronJob 恢复暂停的定时任务
+func ResumeCronJob(name string) error {
+	return setCronJobPaused(name, 0)
+}
+
+// TriggerCronJob 立即执行一次定时任务(不受暂停影响), 上次执行未完成时跳过
+func TriggerCronJob(name string) error {
+	job, err := getCronJob(name)
+	if err != nil {
+		return err
+	}
+	log.Info("[CronJobManager] [%s] trigger.", name)
+	go job.run()
+	return nil
+}
+
+func setCronJobPaused(name string, paused int32) error {
+	job, err := getCronJob(name)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&job.paused, paused)
+	log.Info("[CronJobManager] [%s] paused:%d", name, paused)
+	return nil
+}
+
+func getCronJob(name string) (*_CronJob, error) {
+	syncCronJobs.RLock()
+	defer syncCronJobs.RUnlock()
+	if job, ok := cronJobs[name]; ok {
+		return job, nil
+	}
+	return nil, fmt.Errorf("cron job %s not found", name)
+}